@@ -2,15 +2,23 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"github.com/Critma/textAnalyzer/analyzer/internal/analyze"
+	"github.com/Critma/textAnalyzer/analyzer/internal/blob"
+	"github.com/Critma/textAnalyzer/analyzer/internal/cache"
 	"github.com/Critma/textAnalyzer/analyzer/internal/config"
 	"github.com/Critma/textAnalyzer/analyzer/internal/models"
 	"github.com/Critma/textAnalyzer/analyzer/internal/routes"
+	"github.com/Critma/textAnalyzer/shared/ratelimit"
+	"github.com/Critma/textAnalyzer/shared/signature"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
@@ -35,15 +43,36 @@ func main() {
 	})
 	defer redisClient.Close()
 
+	analyzeCache := cache.NewAnalyzeCache(redisClient, config.Version, cache.Options{
+		LocalSize: cfg.Cache.LocalSize,
+		LocalTTL:  cfg.Cache.LocalTTL,
+		TTL:       cfg.Cache.TTL,
+	})
+
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	if cfg.Internal.TLS() {
+		tlsConfig, err := internalTLSConfig(cfg.Internal)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to configure internal tls")
+		}
+		httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+
 	app := config.Application{
 		Config:     cfg,
 		Redis:      redisClient,
-		HttpClient: &http.Client{Timeout: 5 * time.Second},
+		Cache:      analyzeCache,
+		HttpClient: httpClient,
+		Limiter:    ratelimit.New(redisClient, "analyzer"),
+		Blobs:      blob.New(cfg.BlobDir),
+		Verifier:   signature.NewVerifier(redisClient, cfg.Internal.Secret, cfg.Internal.MaxSkew),
 	}
 
 	r := gin.Default()
 	jobs := make(chan *models.JsonInput, 100)
-	routes := routes.New(app, jobs)
+	compares := make(chan *models.JsonCompareInput, 100)
+	diffs := make(chan *routes.DiffTask, 100)
+	routes := routes.New(app, jobs, compares, diffs)
 	routes.Mount(r)
 
 	// Graceful shutdown
@@ -68,6 +97,15 @@ func main() {
 		// start analyze workers
 		go analyze.Worker(jobs, app)
 	}
+	// comparisons are long, so they have own workers and don't delay analyzes
+	compareWorkersNum := 2
+	for i := 0; i < compareWorkersNum; i++ {
+		go analyze.CompareWorker(compares, app)
+	}
+	diffWorkersNum := 2
+	for i := 0; i < diffWorkersNum; i++ {
+		go analyze.DiffWorker(diffs)
+	}
 
 	// Wait for interrupt signal
 	<-ctx.Done()
@@ -82,3 +120,27 @@ func main() {
 
 	log.Info().Msg("Server stopped...")
 }
+
+// internalTLSConfig return client tls config of receiver internal listener
+func internalTLSConfig(cfg config.Internal) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.TLSCA != "" {
+		pem, err := os.ReadFile(cfg.TLSCA)
+		if err != nil {
+			return nil, err
+		}
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates in " + cfg.TLSCA)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	if cfg.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
@@ -1,52 +1,178 @@
 package analyze
 
 import (
+	"cmp"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode"
 
 	"github.com/Critma/textAnalyzer/analyzer/internal/models"
+	"github.com/Critma/textAnalyzer/shared/normalize"
 )
 
+var sentenceRegex = regexp.MustCompile(`[.!?]+`)
+
+// topWordsCount is a number of the most frequent words in result
+const topWordsCount = 10
+
 func analyzeText(text string) *models.JsonAnalyze {
-	// Count characters
-	charCount := len(text)
+	return analyzeChunk(text).result()
+}
 
-	// Count words
-	words := strings.Fields(text)
-	wordCount := len(words)
-
-	// Count sentences
-	sentenceRegex := regexp.MustCompile(`[.!?]+`)
-	sentences := sentenceRegex.Split(text, -1)
-	// Filter out empty strings
-	sentenceCount := 0
-	for _, s := range sentences {
-		if strings.TrimSpace(s) != "" {
-			sentenceCount++
-		}
-	}
+// analyzeNormalized analyze text normalized by steps, applied steps are reported in result
+func analyzeNormalized(text string, steps normalize.Steps) *models.JsonAnalyze {
+	result := analyzeText(steps.Apply(text))
+	result.Normalization = steps.Names()
+	return result
+}
+
+// stats of a text part, stats of adjacent parts are merged
+//
+// parts must be split at whitespace, so no word is split between them,
+// a sentence may be split and is counted once on merge
+type stats struct {
+	chars       int
+	words       int
+	wordLetters int
+	// frequencies of lower-cased words without punctuation
+	frequencies map[string]int
+	// minHash of word shingles, shingles crossing a part boundary are lost
+	minHash minHash
+	// sentences between first and last sentence delimiter
+	sentences int
+	// delimited report whether part contains a sentence delimiter,
+	// else head is the whole part and tail is unused
+	delimited bool
+	// head and tail report whether text before first and after last delimiter is not blank
+	head, tail bool
+}
+
+func analyzeChunk(text string) stats {
+	s := stats{chars: len(text), frequencies: make(map[string]int)}
 
-	// Calculate average word length
-	var totalWordLength int
+	words := strings.Fields(text)
+	s.words = len(words)
+	hashes := make([]uint64, 0, len(words))
 	for _, word := range words {
 		// Remove punctuation from word
 		word = strings.TrimFunc(word, func(r rune) bool {
 			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
 		})
 		if word != "" {
-			totalWordLength += len(word)
+			s.wordLetters += len(word)
+			word = strings.ToLower(word)
+			s.frequencies[word]++
+			hashes = append(hashes, hashWord(word))
 		}
 	}
-	var averageWordLength float64
-	if wordCount > 0 {
-		averageWordLength = float64(totalWordLength) / float64(wordCount)
+	// text shorter than a shingle is one shingle
+	for i := 0; i < len(hashes) && (i == 0 || i+shingleSize <= len(hashes)); i++ {
+		s.minHash.add(hashes[i:min(i+shingleSize, len(hashes))])
 	}
 
+	segments := sentenceRegex.Split(text, -1)
+	s.head = notBlank(segments[0])
+	if len(segments) > 1 {
+		s.delimited = true
+		s.tail = notBlank(segments[len(segments)-1])
+		for _, segment := range segments[1 : len(segments)-1] {
+			if notBlank(segment) {
+				s.sentences++
+			}
+		}
+	}
+	return s
+}
+
+// merge return stats of s followed by next, frequencies of s and next are reused
+func (s stats) merge(next stats) stats {
+	merged := stats{
+		chars:       s.chars + next.chars,
+		words:       s.words + next.words,
+		wordLetters: s.wordLetters + next.wordLetters,
+		frequencies: mergeFrequencies(s.frequencies, next.frequencies),
+		minHash:     s.minHash.merge(next.minHash),
+		delimited:   s.delimited || next.delimited,
+	}
+	switch {
+	case !s.delimited && !next.delimited:
+		merged.head = s.head || next.head
+	case !s.delimited:
+		merged.head = s.head || next.head
+		merged.sentences = next.sentences
+		merged.tail = next.tail
+	case !next.delimited:
+		merged.head = s.head
+		merged.sentences = s.sentences
+		merged.tail = s.tail || next.head
+	default:
+		merged.head = s.head
+		merged.sentences = s.sentences + next.sentences
+		// tail of s and head of next are one sentence
+		if s.tail || next.head {
+			merged.sentences++
+		}
+		merged.tail = next.tail
+	}
+	return merged
+}
+
+func (s stats) result() *models.JsonAnalyze {
+	sentenceCount := s.sentences
+	if s.head {
+		sentenceCount++
+	}
+	if s.delimited && s.tail {
+		sentenceCount++
+	}
+	var averageWordLength float64
+	if s.words > 0 {
+		averageWordLength = float64(s.wordLetters) / float64(s.words)
+	}
 	return &models.JsonAnalyze{
-		WordCount:         wordCount,
-		CharCount:         charCount,
+		WordCount:         s.words,
+		CharCount:         s.chars,
 		SentenceCount:     sentenceCount,
 		AverageWordLength: averageWordLength,
+		UniqueWordCount:   len(s.frequencies),
+		TopWords:          topWords(s.frequencies, topWordsCount),
+		Signature:         signature(s.minHash, s.frequencies),
+	}
+}
+
+// mergeFrequencies add the smaller map to the larger one and return it
+func mergeFrequencies(a, b map[string]int) map[string]int {
+	if len(a) < len(b) {
+		a, b = b, a
 	}
+	if a == nil {
+		return b
+	}
+	for word, count := range b {
+		a[word] += count
+	}
+	return a
+}
+
+// topWords return n the most frequent words, equal counts are ordered by word
+func topWords(frequencies map[string]int, n int) []models.WordFrequency {
+	top := make([]models.WordFrequency, 0, len(frequencies))
+	for word, count := range frequencies {
+		top = append(top, models.WordFrequency{Word: word, Count: count})
+	}
+	slices.SortFunc(top, func(a, b models.WordFrequency) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Word, b.Word)
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+func notBlank(text string) bool {
+	return strings.TrimSpace(text) != ""
 }
@@ -0,0 +1,216 @@
+package analyze
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/Critma/textAnalyzer/analyzer/internal/config"
+	"github.com/Critma/textAnalyzer/analyzer/internal/models"
+	"github.com/Critma/textAnalyzer/analyzer/internal/routes"
+	"github.com/Critma/textAnalyzer/analyzer/internal/tokenize"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// shingleSize is a number of words in one shingle of Jaccard similarity
+	shingleSize = 3
+	// minPassageWords is a minimal length of common passage
+	minPassageWords = 4
+	// passagesCount is a number of the longest common passages in result
+	passagesCount = 5
+)
+
+// CompareWorker compare texts of task, send result back
+func CompareWorker(jobs <-chan *models.JsonCompareInput, app config.Application) {
+	for task := range jobs {
+		output := models.JsonCompareOutput{
+			ID:              task.ID,
+			Status:          string(models.Success),
+			Compare:         *compareTexts(task.Left, task.Right),
+			AnalyzerVersion: config.Version,
+		}
+		if err := routes.SendCompareResult(output, app); err != nil {
+			log.Error().Str("event", "send compare result back").Str("id", task.ID).Err(err).Msg("failed to send compare result back")
+		}
+	}
+}
+
+func compareTexts(left, right string) *models.JsonCompare {
+	leftTokens, rightTokens := tokenize.Tokenize(left), tokenize.Tokenize(right)
+	// words are compared by ids, shared by both texts
+	ids := make(map[string]int)
+	leftWords, rightWords := wordIDs(leftTokens, ids), wordIDs(rightTokens, ids)
+
+	distance, runs := alignWords(leftWords, rightWords)
+	result := &models.JsonCompare{
+		Jaccard: jaccard(shingles(leftWords), shingles(rightWords)),
+		Cosine:  tfidfCosine(leftWords, rightWords, len(ids)),
+	}
+	if longest := max(len(leftWords), len(rightWords)); longest > 0 {
+		result.EditDistance = float64(distance) / float64(longest)
+	}
+	for _, run := range runs {
+		start, end := leftTokens[run.left].Start, leftTokens[run.left+run.words-1].End
+		result.Passages = append(result.Passages, models.Passage{
+			Text:        left[start:end],
+			Words:       run.words,
+			LeftOffset:  utf8.RuneCountInString(left[:start]),
+			RightOffset: utf8.RuneCountInString(right[:rightTokens[run.right].Start]),
+		})
+	}
+	return result
+}
+
+// wordIDs return id of every token word, new words are added to ids
+func wordIDs(tokens []tokenize.Token, ids map[string]int) []int {
+	words := make([]int, len(tokens))
+	for i, token := range tokens {
+		id, ok := ids[token.Word]
+		if !ok {
+			id = len(ids)
+			ids[token.Word] = id
+		}
+		words[i] = id
+	}
+	return words
+}
+
+// shingle is a sequence of word ids, shingle of shorter text is padded with -1
+type shingle [shingleSize]int
+
+// shingles return set of shingleSize word sequences, text shorter than a shingle is one shingle
+func shingles(words []int) map[shingle]struct{} {
+	set := make(map[shingle]struct{})
+	for i := 0; i < len(words) && (i == 0 || i+shingleSize <= len(words)); i++ {
+		key := shingle{}
+		for k := range key {
+			key[k] = -1
+			if i+k < len(words) {
+				key[k] = words[i+k]
+			}
+		}
+		set[key] = struct{}{}
+	}
+	return set
+}
+
+// jaccard return size of intersection divided by size of union, 0 for two empty sets
+func jaccard(a, b map[shingle]struct{}) float64 {
+	common := 0
+	for key := range a {
+		if _, ok := b[key]; ok {
+			common++
+		}
+	}
+	union := len(a) + len(b) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+// tfidfCosine return cosine similarity of TF-IDF vectors of two texts,
+// idf is smoothed, so words of both texts keep a weight
+func tfidfCosine(left, right []int, vocabulary int) float64 {
+	leftCounts, rightCounts := make([]float64, vocabulary), make([]float64, vocabulary)
+	for _, word := range left {
+		leftCounts[word]++
+	}
+	for _, word := range right {
+		rightCounts[word]++
+	}
+	var dot, leftNorm, rightNorm float64
+	for word := range vocabulary {
+		documents := 0
+		if leftCounts[word] > 0 {
+			documents++
+		}
+		if rightCounts[word] > 0 {
+			documents++
+		}
+		idf := math.Log(3/float64(1+documents)) + 1
+		l, r := leftCounts[word]*idf, rightCounts[word]*idf
+		dot += l * r
+		leftNorm += l * l
+		rightNorm += r * r
+	}
+	if leftNorm == 0 || rightNorm == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(leftNorm*rightNorm)
+}
+
+// run is a common word sequence starting at left and right word indexes
+type run struct {
+	left, right, words int
+}
+
+// alignWords return word level Levenshtein distance and the longest distinct common runs
+//
+// both are computed in one pass over len(left)*len(right) table, keeping two rows of it
+func alignWords(left, right []int) (int, []run) {
+	// previous and current rows of distances and lengths of common runs ending at cell
+	prevDistance, distance := make([]int, len(right)+1), make([]int, len(right)+1)
+	prevRun, curRun := make([]int, len(right)+2), make([]int, len(right)+2)
+	for j := range prevDistance {
+		prevDistance[j] = j
+	}
+	var runs []run
+	// rows and columns past the end never match, so every run is closed
+	for i := 1; i <= len(left)+1; i++ {
+		if i <= len(left) {
+			distance[0] = i
+		}
+		for j := 1; j <= len(right)+1; j++ {
+			if i <= len(left) && j <= len(right) && left[i-1] == right[j-1] {
+				curRun[j] = prevRun[j-1] + 1
+			} else {
+				curRun[j] = 0
+				// run ending at previous diagonal cell can't be extended
+				if words := prevRun[j-1]; words >= minPassageWords {
+					runs = append(runs, run{left: i - 1 - words, right: j - 1 - words, words: words})
+					if len(runs) > 64*passagesCount {
+						runs = longestRuns(runs, left)
+					}
+				}
+			}
+			if i <= len(left) && j <= len(right) {
+				substitution := prevDistance[j-1]
+				if left[i-1] != right[j-1] {
+					substitution++
+				}
+				distance[j] = min(substitution, prevDistance[j]+1, distance[j-1]+1)
+			}
+		}
+		if i <= len(left) {
+			prevDistance, distance = distance, prevDistance
+		}
+		prevRun, curRun = curRun, prevRun
+	}
+	return prevDistance[len(right)], longestRuns(runs, left)
+}
+
+// longestRuns return up to passagesCount the longest runs of different words, earlier run first among equal
+func longestRuns(runs []run, words []int) []run {
+	slices.SortStableFunc(runs, func(a, b run) int {
+		if c := cmp.Compare(b.words, a.words); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.left, b.left)
+	})
+	result := runs[:0]
+	for _, candidate := range runs {
+		if len(result) == passagesCount {
+			break
+		}
+		duplicate := slices.ContainsFunc(result, func(r run) bool {
+			return slices.Equal(words[r.left:r.left+r.words], words[candidate.left:candidate.left+candidate.words])
+		})
+		if !duplicate {
+			result = append(result, candidate)
+		}
+	}
+	return result
+}
@@ -0,0 +1,166 @@
+package analyze
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Critma/textAnalyzer/analyzer/internal/diff"
+	"github.com/Critma/textAnalyzer/analyzer/internal/models"
+	"github.com/Critma/textAnalyzer/analyzer/internal/routes"
+	"github.com/Critma/textAnalyzer/analyzer/internal/tokenize"
+	"github.com/Critma/textAnalyzer/shared/normalize"
+)
+
+// DiffWorker compute diffs of tasks for waiting handlers
+func DiffWorker(jobs <-chan *routes.DiffTask) {
+	for task := range jobs {
+		// steps are validated by handler
+		leftSteps, _ := normalize.Parse(task.Input.LeftNormalize)
+		rightSteps, _ := normalize.Parse(task.Input.RightNormalize)
+		task.Result <- diffTexts(task.Input.Left, task.Input.Right, leftSteps, rightSteps)
+	}
+}
+
+// diffTexts compare texts as they are written, metrics are computed on texts normalized by their steps
+func diffTexts(left, right string, leftSteps, rightSteps normalize.Steps) *models.JsonDiff {
+	leftStats, rightStats := analyzeChunk(leftSteps.Apply(left)), analyzeChunk(rightSteps.Apply(right))
+	return &models.JsonDiff{
+		Words:     diffUnits(left, right, wordUnits(left), wordUnits(right)),
+		Sentences: diffUnits(left, right, sentenceUnits(left), sentenceUnits(right)),
+		Metrics:   metricsDiff(leftStats, rightStats),
+	}
+}
+
+// unit of text compared by diff, start and end are byte offsets of it in text
+type unit struct {
+	key        string
+	start, end int
+}
+
+// wordUnits return words of text as they are written, without surrounding punctuation
+func wordUnits(text string) []unit {
+	tokens := tokenize.Tokenize(text)
+	units := make([]unit, len(tokens))
+	for i, token := range tokens {
+		units[i] = unit{key: text[token.Start:token.End], start: token.Start, end: token.End}
+	}
+	return units
+}
+
+// sentenceUnits split text after sentence delimiters, sentences are compared with whitespace collapsed
+func sentenceUnits(text string) []unit {
+	var units []unit
+	add := func(start, end int) {
+		sentence := text[start:end]
+		trimmed := strings.TrimSpace(sentence)
+		if trimmed == "" {
+			return
+		}
+		start += strings.Index(sentence, trimmed)
+		units = append(units, unit{key: strings.Join(strings.Fields(trimmed), " "), start: start, end: start + len(trimmed)})
+	}
+	start := 0
+	for _, delimiter := range sentenceRegex.FindAllStringIndex(text, -1) {
+		add(start, delimiter[1])
+		start = delimiter[1]
+	}
+	add(start, len(text))
+	return units
+}
+
+// diffUnits return changes of units of left text to units of right text
+func diffUnits(left, right string, leftUnits, rightUnits []unit) []models.DiffOp {
+	keys := func(units []unit) []string {
+		result := make([]string, len(units))
+		for i, u := range units {
+			result[i] = u.key
+		}
+		return result
+	}
+	ops := make([]models.DiffOp, 0)
+	leftChars, rightChars := &charCounter{text: left}, &charCounter{text: right}
+	for _, hunk := range diff.Diff(keys(leftUnits), keys(rightUnits)) {
+		leftStart, leftEnd := span(left, leftUnits, hunk.A, hunk.ALen)
+		rightStart, rightEnd := span(right, rightUnits, hunk.B, hunk.BLen)
+		ops = append(ops, models.DiffOp{
+			Op:          string(hunk.Op),
+			Left:        left[leftStart:leftEnd],
+			Right:       right[rightStart:rightEnd],
+			LeftOffset:  leftChars.at(leftStart),
+			RightOffset: rightChars.at(rightStart),
+		})
+	}
+	return ops
+}
+
+// span return byte range of count units from i, empty range is at the start of unit i or at the end of text
+func span(text string, units []unit, i, count int) (int, int) {
+	if count > 0 {
+		return units[i].start, units[i+count-1].end
+	}
+	if i < len(units) {
+		return units[i].start, units[i].start
+	}
+	return len(text), len(text)
+}
+
+// charCounter convert increasing byte offsets of text to offsets in chars
+type charCounter struct {
+	text  string
+	pos   int
+	chars int
+}
+
+func (c *charCounter) at(pos int) int {
+	c.chars += utf8.RuneCountInString(c.text[c.pos:pos])
+	c.pos = pos
+	return c.chars
+}
+
+func metricsDiff(left, right stats) models.JsonMetricsDiff {
+	l, r := left.result(), right.result()
+	intDelta := func(left, right int) models.IntDelta {
+		return models.IntDelta{Left: left, Right: right, Delta: right - left}
+	}
+
+	var words []string
+	for _, w := range slices.Concat(l.TopWords, r.TopWords) {
+		if !slices.Contains(words, w.Word) {
+			words = append(words, w.Word)
+		}
+	}
+	topWords := make([]models.WordDelta, 0, len(words))
+	for _, word := range words {
+		topWords = append(topWords, models.WordDelta{
+			Word:  word,
+			Left:  left.frequencies[word],
+			Right: right.frequencies[word],
+			Delta: right.frequencies[word] - left.frequencies[word],
+		})
+	}
+	slices.SortFunc(topWords, func(a, b models.WordDelta) int {
+		if c := cmp.Compare(abs(b.Delta), abs(a.Delta)); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Word, b.Word)
+	})
+
+	return models.JsonMetricsDiff{
+		WordCount:     intDelta(l.WordCount, r.WordCount),
+		CharCount:     intDelta(l.CharCount, r.CharCount),
+		SentenceCount: intDelta(l.SentenceCount, r.SentenceCount),
+		AverageWordLength: models.FloatDelta{
+			Left:  l.AverageWordLength,
+			Right: r.AverageWordLength,
+			Delta: r.AverageWordLength - l.AverageWordLength,
+		},
+		UniqueWordCount: intDelta(l.UniqueWordCount, r.UniqueWordCount),
+		TopWords:        topWords,
+	}
+}
+
+func abs(x int) int {
+	return max(x, -x)
+}
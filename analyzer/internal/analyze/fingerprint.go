@@ -0,0 +1,103 @@
+package analyze
+
+import "github.com/Critma/textAnalyzer/analyzer/internal/models"
+
+// minHashSize is a number of hash functions of MinHash signature
+const minHashSize = 128
+
+// minHashSeeds are multipliers and increments of MinHash hash functions, odd multipliers keep them bijective
+//
+// seeds are fixed, signatures of different analyzer instances must be comparable
+var minHashSeeds = func() (seeds [minHashSize][2]uint64) {
+	state := uint64(0x5eed)
+	for i := range seeds {
+		state += 0x9e3779b97f4a7c15
+		seeds[i][0] = mix64(state) | 1
+		state += 0x9e3779b97f4a7c15
+		seeds[i][1] = mix64(state)
+	}
+	return seeds
+}()
+
+// minHash keep the minimal hash of shingles for every hash function, nil is a signature of text without shingles
+type minHash []uint32
+
+// add shingle of hashed words to signature
+func (m *minHash) add(words []uint64) {
+	if *m == nil {
+		*m = make(minHash, minHashSize)
+		for i := range *m {
+			(*m)[i] = ^uint32(0)
+		}
+	}
+	var h uint64
+	for _, word := range words {
+		h = mix64(h ^ word)
+	}
+	for i, seed := range minHashSeeds {
+		if v := uint32((h*seed[0] + seed[1]) >> 32); v < (*m)[i] {
+			(*m)[i] = v
+		}
+	}
+}
+
+// merge return signature of union of shingles of m and other
+func (m minHash) merge(other minHash) minHash {
+	if m == nil {
+		return other
+	}
+	for i, v := range other {
+		m[i] = min(m[i], v)
+	}
+	return m
+}
+
+// simHash return 64 bit SimHash of words weighted by frequency
+func simHash(frequencies map[string]int) uint64 {
+	var weights [64]int
+	for word, count := range frequencies {
+		h := hashWord(word)
+		for bit := range weights {
+			if h&(1<<bit) != 0 {
+				weights[bit] += count
+			} else {
+				weights[bit] -= count
+			}
+		}
+	}
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// signature return MinHash and SimHash of text stats, nil if text has no words
+func signature(m minHash, frequencies map[string]int) *models.JsonSignature {
+	if m == nil {
+		return nil
+	}
+	return &models.JsonSignature{MinHash: m, SimHash: simHash(frequencies)}
+}
+
+// hashWord return FNV-1a hash of word, mixed to spread bits
+func hashWord(word string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(word); i++ {
+		h ^= uint64(word[i])
+		h *= 1099511628211
+	}
+	return mix64(h)
+}
+
+// mix64 is a splitmix64 finalizer
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,102 @@
+package analyze
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/Critma/textAnalyzer/analyzer/internal/models"
+	"github.com/Critma/textAnalyzer/analyzer/internal/split"
+	"github.com/Critma/textAnalyzer/shared/normalize"
+	"golang.org/x/sync/errgroup"
+)
+
+// chunk of text, seq is its position in the document
+type chunk struct {
+	seq  int
+	text string
+}
+
+type chunkStats struct {
+	seq   int
+	size  int
+	stats stats
+}
+
+// analyzeReader analyze text of r as a stream of chunks of about chunkSize bytes,
+// so large documents are not read in memory at once
+//
+// chunks are normalized by steps and analyzed by workers in parallel, and merged in document order,
+// progress is called with a number of analyzed bytes after each merged chunk
+func analyzeReader(ctx context.Context, r io.Reader, chunkSize, workers int, steps normalize.Steps, progress func(analyzed int64)) (*models.JsonAnalyze, error) {
+	g, ctx := errgroup.WithContext(ctx)
+	chunks := make(chan chunk, workers)
+	results := make(chan chunkStats, workers)
+
+	g.Go(func() error {
+		defer close(chunks)
+		return readChunks(ctx, r, chunkSize, chunks)
+	})
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		g.Go(func() error {
+			defer wg.Done()
+			for c := range chunks {
+				select {
+				case results <- chunkStats{seq: c.seq, size: len(c.text), stats: analyzeChunk(steps.Apply(c.text))}:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// chunks may complete out of order, stats are merged in document order
+	var total stats
+	var analyzed int64
+	pending := make(map[int]chunkStats)
+	next := 0
+	for result := range results {
+		pending[result.seq] = result
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			total = total.merge(ready.stats)
+			analyzed += int64(ready.size)
+			if progress != nil {
+				progress(analyzed)
+			}
+		}
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	result := total.result()
+	result.Normalization = steps.Names()
+	return result, nil
+}
+
+// readChunks split text of r into chunks and send them in order, until r is read or ctx is done
+//
+// chunk is cut after its last whitespace, so no word is split
+func readChunks(ctx context.Context, r io.Reader, chunkSize int, chunks chan<- chunk) error {
+	scanner := split.NewScanner(r, chunkSize)
+	for seq := 0; scanner.Scan(); seq++ {
+		select {
+		case chunks <- chunk{seq: seq, text: scanner.Text()}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return scanner.Err()
+}
@@ -1,30 +1,82 @@
 package analyze
 
 import (
-	"github.com/Critma/textAnalyzer/analyzer/internal/cache"
+	"context"
+	"time"
+
 	"github.com/Critma/textAnalyzer/analyzer/internal/config"
 	"github.com/Critma/textAnalyzer/analyzer/internal/models"
 	"github.com/Critma/textAnalyzer/analyzer/internal/routes"
+	"github.com/Critma/textAnalyzer/shared/normalize"
 	"github.com/rs/zerolog/log"
 )
 
 // Worker analyze task, send result back
 func Worker(jobs <-chan *models.JsonInput, app config.Application) {
 	for task := range jobs {
-		// Analyze text
-		analyze := analyzeText(task.Text)
-
-		// Cache result
-		cache.SetInRedis(analyze, app.Redis, task.Text)
+		// steps are validated by handler
+		steps, _ := normalize.Parse(task.Normalize)
+		output := models.JsonRequestOutput{
+			ID:              task.ID,
+			Status:          string(models.Success),
+			AnalyzerVersion: config.Version,
+		}
+		if task.BlobID != "" {
+			analyze, err := analyzeBlob(app, task, steps)
+			if err != nil {
+				log.Error().Str("event", "analyze blob").Str("id", task.ID).Str("blob", task.BlobID).Err(err).Msg("failed to analyze blob")
+				output.Status = string(models.Failed)
+			} else {
+				output.Analyze = *analyze
+			}
+		} else {
+			// Analyze text once for concurrent tasks of the same text and normalization, cache result
+			output.Analyze = *app.Cache.Load(task.Text, steps, func() *models.JsonAnalyze {
+				return analyzeNormalized(task.Text, steps)
+			})
+		}
 
 		// Send result back
-		output := models.JsonRequestOutput{
-			ID:      task.ID,
-			Status:  string(models.Success),
-			Analyze: *analyze,
+		err := routes.SendResult(output, app)
+		if err == routes.ErrGone && task.Text != "" {
+			// text is evicted by receiver when request is erased, drop analyze cached since then
+			if err := app.Cache.Delete(task.Text, steps); err != nil {
+				log.Error().Str("event", "send result back").Str("id", task.ID).Err(err).Msg("failed to evict erased text")
+			}
+			continue
 		}
-		if err := routes.SendResult(output, app.HttpClient, app.Config.ReceiverAddr); err != nil {
+		if err != nil {
 			log.Error().Str("event", "send result back").Any("obj", output).Err(err).Msg("failed to send result back")
 		}
 	}
 }
+
+// progressInterval is a minimal interval between progress reports of one document
+const progressInterval = time.Second
+
+// analyzeBlob analyze large document in chunks and report progress to receiver, result is not cached
+func analyzeBlob(app config.Application, task *models.JsonInput, steps normalize.Steps) (*models.JsonAnalyze, error) {
+	file, err := app.Blobs.Open(task.BlobID)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	reported, lastReport := 0, time.Now()
+	progress := func(analyzed int64) {
+		percent := int(analyzed * 100 / max(info.Size(), 1))
+		// completion is reported by result
+		if percent <= reported || percent >= 100 || time.Since(lastReport) < progressInterval {
+			return
+		}
+		reported, lastReport = percent, time.Now()
+		if err := routes.SendProgress(models.JsonProgressOutput{ID: task.ID, Progress: percent}, app); err != nil {
+			log.Error().Str("event", "send progress").Str("id", task.ID).Err(err).Msg("failed to send progress")
+		}
+	}
+	return analyzeReader(context.Background(), file, app.Config.Limits.ChunkBytes, app.Config.Limits.ChunkWorkers, steps, progress)
+}
@@ -0,0 +1,46 @@
+package blob
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var ErrInvalidID = errors.New("invalid blob id")
+
+// Store read documents uploaded to receiver, directory is shared by both services
+type Store struct {
+	dir string
+}
+
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Open return reader of blob content
+func (s *Store) Open(id string) (*os.File, error) {
+	if !validID(id) {
+		return nil, ErrInvalidID
+	}
+	return os.Open(filepath.Join(s.dir, id))
+}
+
+// validID report whether id is a canonical lower-case uuid, so it can't point outside of store dir
+func validID(id string) bool {
+	if len(id) != 36 {
+		return false
+	}
+	for i, c := range id {
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+				return false
+			}
+		}
+	}
+	return true
+}
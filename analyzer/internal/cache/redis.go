@@ -2,64 +2,197 @@ package cache
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"hash/fnv"
-	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Critma/textAnalyzer/analyzer/internal/metrics"
 	"github.com/Critma/textAnalyzer/analyzer/internal/models"
+	"github.com/Critma/textAnalyzer/shared/normalize"
+	"github.com/Critma/textAnalyzer/shared/tiered"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
 const analyzerObj = "analyze"
 
-var ttl time.Duration = 10 * time.Minute
+var ErrInvalidVersion = errors.New("invalid version")
 
-// GetFromRedis return pointer to JsonAnalyze
+// Options of two-tier cache
+type Options = tiered.Options
+
+// AnalyzeCache cache JsonAnalyze by normalized text, normalization steps and analyzer version
+type AnalyzeCache struct {
+	analyzes *tiered.Cache[hashEntry]
+	rdb      *redis.Client
+	version  string
+}
+
+// NewAnalyzeCache return two-tier cache of analyzes made by analyzer version
+func NewAnalyzeCache(rdb *redis.Client, version string, opts Options) *AnalyzeCache {
+	opts.Name = analyzerObj
+	opts.Observe = metrics.ObserveCache
+	opts.NotFound = nil
+	return &AnalyzeCache{
+		analyzes: tiered.New[hashEntry](&remote{rdb: rdb}, opts),
+		rdb:      rdb,
+		version:  version,
+	}
+}
+
+// Get return pointer to JsonAnalyze of text analyzed with normalization steps
 //
-// return nil if redis error or key(text) not presented
-func GetFromRedis(rdb *redis.Client, text string) *models.JsonAnalyze {
-	var result models.JsonAnalyze
-	val, err := rdb.Get(context.Background(), getRedisKey(text)).Result()
-	if err != nil {
-		if err != redis.Nil {
-			log.Error().Str("event", "get from redis").Str("text", text).Err(err).Msg("cache not found")
-		}
+// return nil if cache error or key not presented
+func (c *AnalyzeCache) Get(text string, steps normalize.Steps) *models.JsonAnalyze {
+	key, textHash := c.key(text, steps)
+	entry, ok, _ := c.analyzes.Get(context.Background(), key)
+	if !ok || !c.verify(key, textHash, entry) {
 		return nil
-	} else {
-		err = json.Unmarshal([]byte(val), &result)
+	}
+	return &entry.Analyze
+}
+
+// Load return cached analyze of text normalized by steps, or call analyze once for concurrent callers and cache the result
+func (c *AnalyzeCache) Load(text string, steps normalize.Steps, analyze func() *models.JsonAnalyze) *models.JsonAnalyze {
+	key, textHash := c.key(text, steps)
+	entry, _ := c.analyzes.Load(context.Background(), key, func() (hashEntry, error) {
+		return hashEntry{TextHash: textHash, Analyze: *analyze()}, nil
+	})
+	if !c.verify(key, textHash, entry) {
+		entry = hashEntry{TextHash: textHash, Analyze: *analyze()}
+		c.analyzes.Set(context.Background(), key, entry)
+	}
+	return &entry.Analyze
+}
+
+// Delete remove analyze of text normalized by steps
+//
+// entries of previous versions are never read and expire by ttl
+func (c *AnalyzeCache) Delete(text string, steps normalize.Steps) error {
+	key, _ := c.key(text, steps)
+	return c.analyzes.Delete(context.Background(), key)
+}
+
+// verify report whether entry is an analyze of normalized text with textHash, other entry is removed
+func (c *AnalyzeCache) verify(key, textHash string, entry hashEntry) bool {
+	if entry.TextHash == textHash {
+		return true
+	}
+	log.Error().Str("event", "get from cache").Str("key", key).Str("textHash", textHash).Str("storedHash", entry.TextHash).Msg("cached text hash mismatch")
+	if err := c.analyzes.Delete(context.Background(), key); err != nil {
+		log.Error().Str("event", "get from cache").Str("key", key).Err(err).Msg("failed to delete mismatched entry")
+	}
+	return false
+}
+
+// FlushVersion remove every entry of analyzer version, return number of removed redis keys
+func (c *AnalyzeCache) FlushVersion(version string) (int64, error) {
+	if !validVersion(version) {
+		return 0, ErrInvalidVersion
+	}
+	prefix := version + ":"
+	c.analyzes.DeleteLocal(func(key string) bool { return strings.HasPrefix(key, prefix) })
+
+	var deleted int64
+	iter := c.rdb.Scan(context.Background(), 0, getRedisKey(version, "*"), 100).Iterator()
+	batch := make([]string, 0, 100)
+	for iter.Next(context.Background()) {
+		batch = append(batch, iter.Val())
+		if len(batch) == cap(batch) {
+			n, err := c.rdb.Del(context.Background(), batch...).Result()
+			if err != nil {
+				return deleted, err
+			}
+			deleted += n
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, err
+	}
+	if len(batch) > 0 {
+		n, err := c.rdb.Del(context.Background(), batch...).Result()
 		if err != nil {
-			log.Error().Str("event", "unmarshall redis value").Str("toUnmarshall", val).Err(err).Msg("Failed to unmarshal")
-			return nil
+			return deleted, err
 		}
+		deleted += n
 	}
-	return &result
+	return deleted, nil
 }
 
-// SetInRedis set JsonAnalyze on key (text)
-func SetInRedis(result *models.JsonAnalyze, rdb *redis.Client, text string) {
-	redisVal, err := json.Marshal(result)
+// key return {version}:{hash of normalized text and steps} and hex SHA-256 of normalized text
+func (c *AnalyzeCache) key(text string, steps normalize.Steps) (key, textHash string) {
+	normalized := steps.Apply(text)
+	sum := sha256.Sum256([]byte(normalized))
+	return fmt.Sprintf("%s:%s", c.version, steps.HashNormalized(normalized)), hex.EncodeToString(sum[:])
+}
+
+// hashEntry is a cached analyze, TextHash is a hex SHA-256 of normalized text verified on read
+type hashEntry struct {
+	TextHash string             `json:"textHash"`
+	Analyze  models.JsonAnalyze `json:"analyze"`
+}
+
+// remote store analyze in redis key obj:{version}:{keyHash}
+type remote struct {
+	rdb *redis.Client
+}
+
+func (r *remote) Get(ctx context.Context, key string) (tiered.Entry[hashEntry], error) {
+	var entry tiered.Entry[hashEntry]
+	version, keyHash := splitKey(key)
+	val, err := r.rdb.Get(ctx, getRedisKey(version, keyHash)).Result()
 	if err != nil {
-		log.Error().Str("event", "marshall value").Any("obj", *result).Err(err).Msg("Failed to marshal object")
-		return
+		if err == redis.Nil {
+			return entry, tiered.ErrMiss
+		}
+		return entry, err
+	}
+	if err := json.Unmarshal([]byte(val), &entry.Value); err != nil {
+		return entry, err
 	}
-	err = rdb.Set(context.Background(), getRedisKey(text), string(redisVal), ttl).Err()
+	return entry, nil
+}
+
+func (r *remote) Set(ctx context.Context, key string, entry tiered.Entry[hashEntry], ttl time.Duration) error {
+	version, keyHash := splitKey(key)
+	redisVal, err := json.Marshal(entry.Value)
 	if err != nil {
-		log.Error().Str("event", "redis set").Any("obj", *result).Err(err).Msg("Failed to marshal object")
-		return
+		return err
 	}
+	return r.rdb.Set(ctx, getRedisKey(version, keyHash), string(redisVal), ttl).Err()
 }
 
-// getRedisKey hash the text with fnv
-//
-// return obj:{hash}
-func getRedisKey(text string) string {
-	h := fnv.New32a()
-	h.Write([]byte(text))
-	hashValue := h.Sum32()
+func (r *remote) Delete(ctx context.Context, key string) error {
+	version, keyHash := splitKey(key)
+	return r.rdb.Del(ctx, getRedisKey(version, keyHash)).Err()
+}
+
+// splitKey split {version}:{keyHash}
+func splitKey(key string) (version, keyHash string) {
+	version, keyHash, _ = strings.Cut(key, ":")
+	return version, keyHash
+}
 
-	key := fmt.Sprintf("%s:%s", analyzerObj, strconv.FormatUint(uint64(hashValue), 10))
+// getRedisKey return obj:{version}:{keyHash}
+func getRedisKey(version, keyHash string) string {
+	key := fmt.Sprintf("%s:%s:%s", analyzerObj, version, keyHash)
 	return key
 }
+
+// validVersion report whether version has no redis pattern characters
+func validVersion(version string) bool {
+	if version == "" {
+		return false
+	}
+	for _, r := range version {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -2,28 +2,128 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
 
+	"github.com/Critma/textAnalyzer/analyzer/internal/blob"
+	"github.com/Critma/textAnalyzer/analyzer/internal/cache"
+	"github.com/Critma/textAnalyzer/shared/ratelimit"
+	"github.com/Critma/textAnalyzer/shared/signature"
 	"github.com/redis/go-redis/v9"
 )
 
+// Version of analyze logic, bump it when analyze results change
+//
+// receiver reuse results of the same text only within one version
+const Version = "3"
+
 type Application struct {
 	Config     *Config
 	Redis      *redis.Client
+	Cache      *cache.AnalyzeCache
 	HttpClient *http.Client
+	Limiter    *ratelimit.Limiter
+	Blobs      *blob.Store
+	// Verifier check signatures of cache requests from receiver
+	Verifier *signature.Verifier
 }
 
 type Config struct {
 	Addr         string
 	ReceiverAddr string
 	RedisAddr    string
+	Cache        Cache
+	Internal     Internal
+	RateLimit    RateLimit
+	Limits       Limits
+	// BlobDir is shared with receiver, large documents are read from it
+	BlobDir string
+}
+
+// Limits of request size and document analysis
+type Limits struct {
+	// MaxBodyBytes of json requests
+	MaxBodyBytes int64
+	// ChunkBytes of document analyzed at once
+	ChunkBytes int
+	// ChunkWorkers analyze chunks of one document in parallel
+	ChunkWorkers int
+	// CompareWords is a maximal number of words in each compared text,
+	// edit distance and common passages take time proportional to product of lengths
+	CompareWords int
+	// DiffWords is a maximal number of words in each diffed text,
+	// diff is answered synchronously and takes time proportional to length and number of changes
+	DiffWords int
+}
+
+// RateLimit of analyzer api, zero rate disable a bucket
+//
+// receiver replicas are the only clients, so limits are off by default
+type RateLimit struct {
+	// Client bucket is per client ip
+	Client ratelimit.Limit
+	// Global bucket is shared by all clients
+	Global ratelimit.Limit
+}
+
+// Internal is a connection to receiver service internal listener
+type Internal struct {
+	// ReceiverAddr of internal listener, RECEIVER_ADDR if not set
+	ReceiverAddr string
+	// Secret sign callbacks and cache requests from receiver, required
+	Secret string `json:"-"`
+	// MaxSkew of timestamp of requests from receiver
+	MaxSkew time.Duration
+	// TLSCA verify receiver certificate, TLSCert and TLSKey are a client certificate (mTLS)
+	TLSCA   string
+	TLSCert string
+	TLSKey  string
+}
+
+// TLS report whether receiver internal listener is served over https
+func (i Internal) TLS() bool {
+	return i.TLSCA != "" || i.TLSCert != ""
+}
+
+// Cache size and ttl of two-tier analyze cache
+type Cache struct {
+	LocalSize int
+	LocalTTL  time.Duration
+	TTL       time.Duration
 }
 
 const (
 	RECEIVER_ADDR = "RECEIVER_ADDR"
 	ANALYZER_ADDR = "ANALYZER_ADDR"
 	REDIS_ADDR    = "REDIS_ADDR"
+
+	CACHE_LOCAL_SIZE = "CACHE_LOCAL_SIZE"
+	CACHE_LOCAL_TTL  = "CACHE_LOCAL_TTL"
+	CACHE_TTL        = "CACHE_TTL"
+
+	RECEIVER_INTERNAL_ADDR = "RECEIVER_INTERNAL_ADDR"
+	INTERNAL_SECRET        = "INTERNAL_SECRET"
+	INTERNAL_MAX_SKEW      = "INTERNAL_MAX_SKEW"
+	INTERNAL_TLS_CA        = "INTERNAL_TLS_CA"
+	INTERNAL_TLS_CERT      = "INTERNAL_TLS_CERT"
+	INTERNAL_TLS_KEY       = "INTERNAL_TLS_KEY"
+
+	RATE_LIMIT_RPS          = "RATE_LIMIT_RPS"
+	RATE_LIMIT_BURST        = "RATE_LIMIT_BURST"
+	RATE_LIMIT_GLOBAL_RPS   = "RATE_LIMIT_GLOBAL_RPS"
+	RATE_LIMIT_GLOBAL_BURST = "RATE_LIMIT_GLOBAL_BURST"
+
+	MAX_BODY_BYTES        = "MAX_BODY_BYTES"
+	ANALYZE_CHUNK_BYTES   = "ANALYZE_CHUNK_BYTES"
+	ANALYZE_CHUNK_WORKERS = "ANALYZE_CHUNK_WORKERS"
+	COMPARE_MAX_WORDS     = "COMPARE_MAX_WORDS"
+	DIFF_MAX_WORDS        = "DIFF_MAX_WORDS"
+	BLOB_DIR              = "BLOB_DIR"
 )
 
 func Load() (*Config, error) {
@@ -46,5 +146,114 @@ func Load() (*Config, error) {
 		return nil, errors.New("failed to get " + REDIS_ADDR)
 	}
 	cfg.RedisAddr = addr
+
+	var err error
+	if cfg.Cache.LocalSize, err = getEnvInt(CACHE_LOCAL_SIZE, 1000); err != nil {
+		return nil, err
+	}
+	if cfg.Cache.LocalTTL, err = getEnvDuration(CACHE_LOCAL_TTL, time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.Cache.TTL, err = getEnvDuration(CACHE_TTL, 10*time.Minute); err != nil {
+		return nil, err
+	}
+
+	cfg.Internal.ReceiverAddr = cfg.ReceiverAddr
+	if addr, ok := os.LookupEnv(RECEIVER_INTERNAL_ADDR); ok && addr != "" {
+		cfg.Internal.ReceiverAddr = addr
+	}
+	cfg.Internal.Secret = os.Getenv(INTERNAL_SECRET)
+	if cfg.Internal.Secret == "" {
+		return nil, errors.New(INTERNAL_SECRET + " must be set")
+	}
+	if cfg.Internal.MaxSkew, err = getEnvDuration(INTERNAL_MAX_SKEW, 5*time.Minute); err != nil {
+		return nil, err
+	}
+	cfg.Internal.TLSCA = os.Getenv(INTERNAL_TLS_CA)
+	cfg.Internal.TLSCert = os.Getenv(INTERNAL_TLS_CERT)
+	cfg.Internal.TLSKey = os.Getenv(INTERNAL_TLS_KEY)
+	if (cfg.Internal.TLSCert == "") != (cfg.Internal.TLSKey == "") {
+		return nil, errors.New(INTERNAL_TLS_CERT + " and " + INTERNAL_TLS_KEY + " must be set together")
+	}
+
+	if cfg.RateLimit.Client.Rate, err = getEnvFloat(RATE_LIMIT_RPS, 0); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.Client.Burst, err = getEnvInt(RATE_LIMIT_BURST, 0); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.Global.Rate, err = getEnvFloat(RATE_LIMIT_GLOBAL_RPS, 0); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.Global.Burst, err = getEnvInt(RATE_LIMIT_GLOBAL_BURST, 0); err != nil {
+		return nil, err
+	}
+
+	maxBody, err := getEnvInt(MAX_BODY_BYTES, 4<<20)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Limits.MaxBodyBytes = int64(maxBody)
+	if cfg.Limits.ChunkBytes, err = getEnvInt(ANALYZE_CHUNK_BYTES, 1<<20); err != nil {
+		return nil, err
+	}
+	if cfg.Limits.ChunkBytes <= 0 {
+		return nil, errors.New(ANALYZE_CHUNK_BYTES + " must be positive")
+	}
+	if cfg.Limits.ChunkWorkers, err = getEnvInt(ANALYZE_CHUNK_WORKERS, runtime.NumCPU()); err != nil {
+		return nil, err
+	}
+	if cfg.Limits.ChunkWorkers <= 0 {
+		return nil, errors.New(ANALYZE_CHUNK_WORKERS + " must be positive")
+	}
+	if cfg.Limits.CompareWords, err = getEnvInt(COMPARE_MAX_WORDS, 20000); err != nil {
+		return nil, err
+	}
+	if cfg.Limits.DiffWords, err = getEnvInt(DIFF_MAX_WORDS, 5000); err != nil {
+		return nil, err
+	}
+	cfg.BlobDir = os.Getenv(BLOB_DIR)
+	if cfg.BlobDir == "" {
+		cfg.BlobDir = filepath.Join(os.TempDir(), "textanalyzer-blobs")
+	}
 	return cfg, nil
 }
+
+// getEnvInt return int env value or def if not set
+func getEnvInt(key string, def int) (int, error) {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// getEnvFloat return float env value or def if not set
+func getEnvFloat(key string, def float64) (float64, error) {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// getEnvDuration return duration env value (e.g. 30s, 1h) or def if not set
+func getEnvDuration(key string, def time.Duration) (time.Duration, error) {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,173 @@
+package diff
+
+type Op string
+
+const (
+	Insert  Op = "insert"
+	Delete  Op = "delete"
+	Replace Op = "replace"
+)
+
+// Hunk is a change of units a[A:A+ALen] to b[B:B+BLen], Insert has empty a part and Delete has empty b part
+type Hunk struct {
+	Op   Op
+	A    int
+	ALen int
+	B    int
+	BLen int
+}
+
+// Diff return the shortest edit script of a to b as hunks of changed units, in order,
+// adjacent deletions and insertions are joined to Replace
+//
+// units are compared by Myers algorithm in linear space,
+// see "An O(ND) Difference Algorithm and Its Variations", E. Myers, 1986
+func Diff(a, b []string) []Hunk {
+	d := &differ{a: a, b: b, aChanged: make([]bool, len(a)), bChanged: make([]bool, len(b))}
+	d.compare(0, len(a), 0, len(b))
+
+	var hunks []Hunk
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && !d.aChanged[i] && !d.bChanged[j] {
+			i++
+			j++
+			continue
+		}
+		h := Hunk{A: i, B: j}
+		for i < len(a) && d.aChanged[i] {
+			i++
+		}
+		for j < len(b) && d.bChanged[j] {
+			j++
+		}
+		h.ALen, h.BLen = i-h.A, j-h.B
+		switch {
+		case h.ALen == 0:
+			h.Op = Insert
+		case h.BLen == 0:
+			h.Op = Delete
+		default:
+			h.Op = Replace
+		}
+		hunks = append(hunks, h)
+	}
+	return hunks
+}
+
+// differ mark units of a and b which are not in their longest common subsequence
+type differ struct {
+	a, b               []string
+	aChanged, bChanged []bool
+}
+
+// compare mark changed units of a[aLo:aHi] and b[bLo:bHi]
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// common prefix and suffix are not changed
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		aLo++
+		bLo++
+	}
+	for aLo < aHi && bLo < bHi && d.a[aHi-1] == d.b[bHi-1] {
+		aHi--
+		bHi--
+	}
+	if aLo == aHi || bLo == bHi {
+		d.change(aLo, aHi, bLo, bHi)
+		return
+	}
+	x, y, ok := d.bisect(aLo, aHi, bLo, bHi)
+	if !ok {
+		d.change(aLo, aHi, bLo, bHi)
+		return
+	}
+	d.compare(aLo, x, bLo, y)
+	d.compare(x, aHi, y, bHi)
+}
+
+// change mark all units of a[aLo:aHi] and b[bLo:bHi] changed
+func (d *differ) change(aLo, aHi, bLo, bHi int) {
+	for i := aLo; i < aHi; i++ {
+		d.aChanged[i] = true
+	}
+	for j := bLo; j < bHi; j++ {
+		d.bChanged[j] = true
+	}
+}
+
+// bisect find a point (x, y) of the shortest edit path of a[aLo:aHi] to b[bLo:bHi],
+// where paths searched forward from the start and backward from the end meet,
+// ok is false if ranges have no common units
+func (d *differ) bisect(aLo, aHi, bLo, bHi int) (int, int, bool) {
+	n, m := aHi-aLo, bHi-bLo
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	// forward[k] and backward[k] are the furthest x reached on diagonal k, -1 if not reached
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for k := range forward {
+		forward[k], backward[k] = -1, -1
+	}
+	forward[offset+1], backward[offset+1] = 0, 0
+	delta := n - m
+	// paths meet on a forward step if delta is odd
+	odd := delta%2 != 0
+	// diagonals out of the edit graph are skipped
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+
+	for e := 0; e < maxD; e++ {
+		for k := -e + fStart; k <= e-fEnd; k += 2 {
+			var x int
+			if k == -e || (k != e && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aLo+x] == d.b[bLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case odd:
+				if bk := offset + delta - k; bk >= 0 && bk < len(backward) && backward[bk] != -1 && x >= n-backward[bk] {
+					return aLo + x, bLo + y, true
+				}
+			}
+		}
+		for k := -e + bStart; k <= e-bEnd; k += 2 {
+			var x int
+			if k == -e || (k != e && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && d.a[aHi-x-1] == d.b[bHi-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case !odd:
+				if fk := offset + delta - k; fk >= 0 && fk < len(forward) && forward[fk] != -1 {
+					fx := forward[fk]
+					fy := fx - (fk - offset)
+					if fx >= n-x {
+						return aLo + fx, bLo + fy, true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,173 @@
+package kwic
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Critma/textAnalyzer/analyzer/internal/models"
+	"github.com/Critma/textAnalyzer/analyzer/internal/split"
+	"github.com/Critma/textAnalyzer/analyzer/internal/stem"
+	"github.com/Critma/textAnalyzer/analyzer/internal/tokenize"
+)
+
+// Query of concordance, Term is a lower-cased word, matched by stem if Stems,
+// page of Limit occurrences starting from Offset is returned
+type Query struct {
+	Term   string
+	Stems  bool
+	Window int
+	Limit  int
+	Offset int
+}
+
+// Find return occurrences of term in text of r with Window words of context on each side,
+// text is read in chunks of about chunkSize bytes, so large documents are not read in memory at once,
+// text with a word longer than split.MaxWordBytes is split.ErrWordTooLong
+func Find(r io.Reader, chunkSize int, q Query) (*models.JsonKwicOutput, error) {
+	f := &finder{query: q, stems: make(map[string]string)}
+	if q.Stems {
+		f.stem = stem.Stem(q.Term)
+	}
+	scanner := split.NewScanner(r, chunkSize)
+	for scanner.Scan() {
+		f.add(scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	f.flush(true)
+	return f.output(), nil
+}
+
+// entry is a word of text and the text following it up to the next word
+type entry struct {
+	word  string
+	piece string
+	// size of word as it is written, in bytes of piece
+	size int
+	// offset of word in text in chars
+	offset int
+}
+
+// finder keep the last words of text, as many as needed for context of found and future occurrences
+type finder struct {
+	query Query
+	stem  string
+	// stems of words seen, matched by stems
+	stems map[string]string
+	words []entry
+	// pending are indexes of words of the page waiting for their right context
+	pending []int
+	total   int
+	chars   int
+	items   []models.JsonKwicLine
+}
+
+// add find occurrences in chunk of text, chunks are added in order
+func (f *finder) add(text string) {
+	tokens := tokenize.Tokenize(text)
+	// text before the first word belongs to the last word of the previous chunk
+	lead := len(text)
+	if len(tokens) > 0 {
+		lead = tokens[0].Start
+	}
+	if n := len(f.words); n > 0 {
+		f.words[n-1].piece += text[:lead]
+	}
+	f.chars += utf8.RuneCountInString(text[:lead])
+
+	for k, token := range tokens {
+		end := len(text)
+		if k+1 < len(tokens) {
+			end = tokens[k+1].Start
+		}
+		f.words = append(f.words, entry{word: token.Word, piece: text[token.Start:end], size: token.End - token.Start, offset: f.chars})
+		f.chars += utf8.RuneCountInString(text[token.Start:end])
+		if f.matches(token.Word) {
+			if f.total >= f.query.Offset && f.total-f.query.Offset < f.query.Limit {
+				f.pending = append(f.pending, len(f.words)-1)
+			}
+			f.total++
+		}
+	}
+	f.flush(false)
+}
+
+func (f *finder) matches(word string) bool {
+	if !f.query.Stems {
+		return word == f.query.Term
+	}
+	stemmed, ok := f.stems[word]
+	if !ok {
+		stemmed = stem.Stem(word)
+		f.stems[word] = stemmed
+	}
+	return stemmed == f.stem
+}
+
+// flush write lines of pending occurrences with complete right context, or all of them if final,
+// and drop words which are no more needed for context
+func (f *finder) flush(final bool) {
+	window := f.query.Window
+	written := 0
+	for _, i := range f.pending {
+		// the last word may be continued by the next chunk
+		if !final && i+window+1 >= len(f.words) {
+			break
+		}
+		f.items = append(f.items, f.line(i))
+		written++
+	}
+	f.pending = f.pending[written:]
+
+	drop := len(f.words) - window
+	if len(f.pending) > 0 {
+		drop = min(drop, f.pending[0]-window)
+	}
+	if drop <= 0 {
+		return
+	}
+	f.words = f.words[:copy(f.words, f.words[drop:])]
+	for k := range f.pending {
+		f.pending[k] -= drop
+	}
+}
+
+func (f *finder) line(i int) models.JsonKwicLine {
+	window := f.query.Window
+	var left, right strings.Builder
+	for _, e := range f.words[max(i-window, 0):i] {
+		left.WriteString(e.piece)
+	}
+	word := f.words[i]
+	right.WriteString(word.piece[word.size:])
+	for _, e := range f.words[i+1 : min(i+window+1, len(f.words))] {
+		right.WriteString(e.piece)
+	}
+	return models.JsonKwicLine{
+		Left:    collapse(left.String()),
+		Keyword: word.piece[:word.size],
+		Right:   collapse(right.String()),
+		Offset:  word.offset,
+	}
+}
+
+// output return found lines with left contexts padded to the same width
+func (f *finder) output() *models.JsonKwicOutput {
+	width := 0
+	for _, item := range f.items {
+		width = max(width, utf8.RuneCountInString(item.Left))
+	}
+	items := make([]models.JsonKwicLine, 0, len(f.items))
+	for _, item := range f.items {
+		item.Left = strings.Repeat(" ", width-utf8.RuneCountInString(item.Left)) + item.Left
+		items = append(items, item)
+	}
+	return &models.JsonKwicOutput{Total: f.total, Items: items}
+}
+
+// collapse replace runs of whitespace, line breaks included, with a single space
+func collapse(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -18,3 +18,27 @@ var requestMetrics = promauto.NewSummaryVec(prometheus.SummaryOpts{
 func ObserveRequest(d time.Duration, statusCode int, path string) {
 	requestMetrics.WithLabelValues(strconv.Itoa(statusCode), path).Observe(d.Seconds())
 }
+
+var rateLimitMetrics = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "analyzer",
+	Subsystem: "ratelimit",
+	Name:      "requests_total",
+	Help:      "Requests checked by rate limiter per bucket scope, result is allowed, throttled or error",
+}, []string{"scope", "result"})
+
+// ObserveRateLimit count rate limiter decision of bucket scope (client, global)
+func ObserveRateLimit(scope, result string) {
+	rateLimitMetrics.WithLabelValues(scope, result).Inc()
+}
+
+var cacheMetrics = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "analyzer",
+	Subsystem: "cache",
+	Name:      "events_total",
+	Help:      "Cache hits, misses, evictions and errors per tier",
+}, []string{"cache", "tier", "event"})
+
+// ObserveCache count cache event (hit, miss, eviction, error) of tier
+func ObserveCache(cache, tier, event string) {
+	cacheMetrics.WithLabelValues(cache, tier, event).Inc()
+}
@@ -11,12 +11,23 @@ var (
 type JsonInput struct {
 	ID   string `json:"id"`
 	Text string `json:"text"`
+	// BlobID reference large document in shared blob storage instead of Text
+	BlobID string `json:"blobId,omitempty"`
+	// Normalize are normalization steps applied before analysis
+	Normalize []string `json:"normalize,omitempty"`
+}
+
+type JsonEvictInput struct {
+	Text string `json:"text"`
+	// Normalize are normalization steps text was analyzed with
+	Normalize []string `json:"normalize,omitempty"`
 }
 
 type JsonRequestOutput struct {
-	ID      string      `json:"id"`
-	Status  string      `json:"status"`
-	Analyze JsonAnalyze `json:"analyze"`
+	ID              string      `json:"id"`
+	Status          string      `json:"status"`
+	Analyze         JsonAnalyze `json:"analyze"`
+	AnalyzerVersion string      `json:"analyzerVersion"`
 }
 
 type JsonAnalyze struct {
@@ -24,4 +35,175 @@ type JsonAnalyze struct {
 	CharCount         int     `json:"charCount,omitempty"`
 	SentenceCount     int     `json:"sentenceCount,omitempty"`
 	AverageWordLength float64 `json:"averageWordLength,omitempty"`
+	UniqueWordCount   int     `json:"uniqueWordCount,omitempty"`
+	// TopWords are the most frequent lower-cased words
+	TopWords []WordFrequency `json:"topWords,omitempty"`
+	// Normalization steps applied to text before analysis, in applied order
+	Normalization []string `json:"normalization,omitempty"`
+	// Signature of text for near-duplicate search, nil if text has no words
+	Signature *JsonSignature `json:"signature,omitempty"`
+}
+
+// JsonSignature is a fingerprint of text, similar texts have similar signatures
+type JsonSignature struct {
+	// MinHash of 3-word shingles, share of equal values estimate Jaccard similarity
+	MinHash []uint32 `json:"minHash"`
+	// SimHash of words weighted by frequency, share of equal bits estimate cosine similarity
+	SimHash uint64 `json:"simHash"`
+}
+
+type WordFrequency struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+// JsonCompareInput is a pair of texts compared by similarity
+type JsonCompareInput struct {
+	ID    string `json:"id"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+type JsonCompareOutput struct {
+	ID              string      `json:"id"`
+	Status          string      `json:"status"`
+	Compare         JsonCompare `json:"compare"`
+	AnalyzerVersion string      `json:"analyzerVersion"`
+}
+
+// JsonCompare is a similarity of two texts, computed on lower-cased words without punctuation
+type JsonCompare struct {
+	// Jaccard similarity of word shingles sets
+	Jaccard float64 `json:"jaccard"`
+	// Cosine similarity of TF-IDF vectors, idf is computed over the two texts
+	Cosine float64 `json:"cosine"`
+	// EditDistance is a word level Levenshtein distance divided by length of the longer text,
+	// 0 for equal texts and 1 for texts without common words
+	EditDistance float64 `json:"editDistance"`
+	// Passages are the longest common word sequences, longest first
+	Passages []Passage `json:"passages,omitempty"`
+}
+
+// Passage is a word sequence found in both texts
+type Passage struct {
+	// Text of passage as it is written in left text
+	Text  string `json:"text"`
+	Words int    `json:"words"`
+	// LeftOffset and RightOffset are offsets of passage in chars
+	LeftOffset  int `json:"leftOffset"`
+	RightOffset int `json:"rightOffset"`
+}
+
+// JsonTokenizeInput are texts tokenized at once
+type JsonTokenizeInput struct {
+	Texts []string `json:"texts"`
+}
+
+type JsonTokenizeOutput struct {
+	// Tokens of every input text, in order of texts
+	Tokens [][]JsonToken `json:"tokens"`
+}
+
+// JsonToken is a lower-cased word of text and its stem, Start and End are byte offsets of the word
+type JsonToken struct {
+	Word  string `json:"word"`
+	Stem  string `json:"stem"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// JsonDiffInput is a pair of versions of text, Left is the old one and Right is the new one,
+// metrics of each version are computed on text normalized by its steps, as in analysis of it
+type JsonDiffInput struct {
+	Left           string   `json:"left"`
+	Right          string   `json:"right"`
+	LeftNormalize  []string `json:"leftNormalize,omitempty"`
+	RightNormalize []string `json:"rightNormalize,omitempty"`
+}
+
+// JsonDiff is a difference of two versions of text
+type JsonDiff struct {
+	// Words are changes of words, compared as written without surrounding punctuation
+	Words []DiffOp `json:"words"`
+	// Sentences are changes of sentences, compared with whitespace collapsed
+	Sentences []DiffOp        `json:"sentences"`
+	Metrics   JsonMetricsDiff `json:"metrics"`
+}
+
+// DiffOp change Left part of left text to Right part of right text,
+// insert has empty Left and delete has empty Right
+type DiffOp struct {
+	// Op is insert, delete or replace
+	Op    string `json:"op"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+	// LeftOffset and RightOffset are offsets of change in chars
+	LeftOffset  int `json:"leftOffset"`
+	RightOffset int `json:"rightOffset"`
+}
+
+// JsonMetricsDiff is every analyze metric of both versions and its change
+type JsonMetricsDiff struct {
+	WordCount         IntDelta   `json:"wordCount"`
+	CharCount         IntDelta   `json:"charCount"`
+	SentenceCount     IntDelta   `json:"sentenceCount"`
+	AverageWordLength FloatDelta `json:"averageWordLength"`
+	UniqueWordCount   IntDelta   `json:"uniqueWordCount"`
+	// TopWords are counts of top words of either version, the largest changes first
+	TopWords []WordDelta `json:"topWords"`
+}
+
+type IntDelta struct {
+	Left  int `json:"left"`
+	Right int `json:"right"`
+	Delta int `json:"delta"`
+}
+
+type FloatDelta struct {
+	Left  float64 `json:"left"`
+	Right float64 `json:"right"`
+	Delta float64 `json:"delta"`
+}
+
+type WordDelta struct {
+	Word  string `json:"word"`
+	Left  int    `json:"left"`
+	Right int    `json:"right"`
+	Delta int    `json:"delta"`
+}
+
+// JsonKwicInput is a concordance query over text or document blob,
+// term is matched by lower-cased word, or by stem if Stems
+type JsonKwicInput struct {
+	Text   string `json:"text"`
+	BlobID string `json:"blobId,omitempty"`
+	Term   string `json:"term"`
+	Stems  bool   `json:"stems,omitempty"`
+	// Window is a number of words of context on each side
+	Window int `json:"window"`
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+type JsonKwicOutput struct {
+	// Total is a number of occurrences of term in text
+	Total int            `json:"total"`
+	Items []JsonKwicLine `json:"items"`
+}
+
+// JsonKwicLine is an occurrence of term, Left is padded with spaces to the widest left context of the page,
+// so keywords are aligned in monospace font
+type JsonKwicLine struct {
+	Left    string `json:"left"`
+	Keyword string `json:"keyword"`
+	Right   string `json:"right"`
+	// Offset of keyword in text in chars
+	Offset int `json:"offset"`
+}
+
+// JsonProgressOutput report progress of document analysis to receiver
+type JsonProgressOutput struct {
+	ID string `json:"id"`
+	// Progress is a percentage of analyzed bytes
+	Progress int `json:"progress"`
 }
@@ -3,22 +3,62 @@ package routes
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 
+	"github.com/Critma/textAnalyzer/analyzer/internal/config"
 	"github.com/Critma/textAnalyzer/analyzer/internal/models"
+	"github.com/Critma/textAnalyzer/shared/signature"
 )
 
-// SendResult send JsonRequestOutput to receiver service
-func SendResult(output models.JsonRequestOutput, httpClient *http.Client, receiverAddr string) error {
+// ErrGone is returned when receiver refuse result of erased or anonymized request
+var ErrGone = errors.New("request is erased")
+
+// SendResult send signed JsonRequestOutput to receiver service internal listener,
+// ErrGone if request is erased or anonymized
+func SendResult(output models.JsonRequestOutput, app config.Application) error {
 	data, _ := json.Marshal(output)
-	addr := fmt.Sprintf("http://%s/api/v1/result", receiverAddr)
-	resp, err := httpClient.Post(addr, "application/json", bytes.NewBuffer(data))
+	return sendInternal(app, "/api/v1/result", data)
+}
+
+// SendCompareResult send signed JsonCompareOutput to receiver service internal listener
+func SendCompareResult(output models.JsonCompareOutput, app config.Application) error {
+	data, _ := json.Marshal(output)
+	return sendInternal(app, "/api/v1/compare/result", data)
+}
+
+// SendProgress send signed progress of document analysis to receiver service internal listener
+func SendProgress(output models.JsonProgressOutput, app config.Application) error {
+	data, _ := json.Marshal(output)
+	return sendInternal(app, "/api/v1/progress", data)
+}
+
+// sendInternal post signed data to path of receiver internal listener
+func sendInternal(app config.Application, path string, data []byte) error {
+	scheme := "http"
+	if app.Config.Internal.TLS() {
+		scheme = "https"
+	}
+	addr := fmt.Sprintf("%s://%s%s", scheme, app.Config.Internal.ReceiverAddr, path)
+	req, err := http.NewRequest("POST", addr, bytes.NewBuffer(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := signature.SignRequest(req, app.Config.Internal.Secret, data); err != nil {
+		return err
+	}
+
+	resp, err := app.HttpClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return ErrGone
+	}
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("receiver service returned status %d", resp.StatusCode)
 	}
@@ -2,12 +2,22 @@ package routes
 
 import (
 	"context"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Critma/textAnalyzer/analyzer/internal/cache"
+	"github.com/Critma/textAnalyzer/analyzer/internal/config"
+	"github.com/Critma/textAnalyzer/analyzer/internal/kwic"
 	"github.com/Critma/textAnalyzer/analyzer/internal/metrics"
 	"github.com/Critma/textAnalyzer/analyzer/internal/models"
+	"github.com/Critma/textAnalyzer/analyzer/internal/split"
+	"github.com/Critma/textAnalyzer/analyzer/internal/stem"
+	"github.com/Critma/textAnalyzer/analyzer/internal/tokenize"
+	"github.com/Critma/textAnalyzer/shared/limits"
+	"github.com/Critma/textAnalyzer/shared/normalize"
+	"github.com/Critma/textAnalyzer/shared/ratelimit"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog/log"
 )
@@ -27,6 +37,11 @@ func (r *Routes) healthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
+// version return version of analyze logic
+func (r *Routes) version(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"version": config.Version})
+}
+
 func (r *Routes) handleAnalyze(c *gin.Context) {
 	start := time.Now()
 	defer func() {
@@ -35,27 +50,43 @@ func (r *Routes) handleAnalyze(c *gin.Context) {
 	var input models.JsonInput
 	// parse request body
 	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
+		if limits.IsTooLarge(err) {
+			log.Error().Str("handler", "handle analyze").Err(err).Msg("Request body is too large")
+			limits.WriteTooLarge(c, "Request body is too large", r.App.Config.Limits.MaxBodyBytes, limits.UnitBytes)
+			return
+		}
 		log.Error().Str("handler", "handle analyze").Err(err).Msg("Invalid request body")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	if input.Text == "" {
+	if input.Text == "" && input.BlobID == "" {
 		log.Error().Str("handler", "handle analyze").Msg("Text cannot be empty")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Text cannot be empty"})
 		return
 	}
 
-	// check if request cached, if not continue
-	cachedResult := cache.GetFromRedis(r.App.Redis, input.Text)
+	steps, err := normalize.Parse(input.Normalize)
+	if err != nil {
+		log.Error().Str("handler", "handle analyze").Strs("normalize", input.Normalize).Err(err).Msg("Invalid normalization")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// check if request cached, if not continue, documents are not cached
+	var cachedResult *models.JsonAnalyze
+	if input.BlobID == "" {
+		cachedResult = r.App.Cache.Get(input.Text, steps)
+	}
 	if cachedResult != nil {
 		c.JSON(http.StatusOK, gin.H{"message": "Success", "cached": true})
 		log.Info().Str("text", input.Text).Msg("use cache")
 		SendResult(models.JsonRequestOutput{
-			ID:      input.ID,
-			Status:  string(models.Success),
-			Analyze: *cachedResult,
-		}, r.App.HttpClient, r.App.Config.ReceiverAddr)
+			ID:              input.ID,
+			Status:          string(models.Success),
+			Analyze:         *cachedResult,
+			AnalyzerVersion: config.Version,
+		}, r.App)
 		return
 	}
 
@@ -64,6 +95,249 @@ func (r *Routes) handleAnalyze(c *gin.Context) {
 	case r.Jobs <- &input:
 		c.JSON(http.StatusOK, gin.H{"message": "Success"})
 	case <-time.After(5 * time.Second):
+		c.Header(ratelimit.RetryAfterHeader, "1")
+		c.JSON(http.StatusTooManyRequests, gin.H{"message": "Unable to send job within timeout"})
+	}
+}
+
+// handleCompare queue comparison of two texts, result is sent to receiver by compare workers
+func (r *Routes) handleCompare(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "POST /compare")
+	}()
+	var input models.JsonCompareInput
+	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
+		if limits.IsTooLarge(err) {
+			log.Error().Str("handler", "handle compare").Err(err).Msg("Request body is too large")
+			limits.WriteTooLarge(c, "Request body is too large", r.App.Config.Limits.MaxBodyBytes, limits.UnitBytes)
+			return
+		}
+		log.Error().Str("handler", "handle compare").Err(err).Msg("Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if input.Left == "" || input.Right == "" {
+		log.Error().Str("handler", "handle compare").Msg("Text cannot be empty")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Text cannot be empty"})
+		return
+	}
+	// every compared word is a field, so fields are a cheap upper bound of words
+	maxWords := r.App.Config.Limits.CompareWords
+	if maxWords > 0 && (len(strings.Fields(input.Left)) > maxWords || len(strings.Fields(input.Right)) > maxWords) {
+		log.Error().Str("handler", "handle compare").Msg("Texts are too long to compare")
+		c.JSON(http.StatusRequestEntityTooLarge, limits.JsonLimitError{Error: "Texts are too long to compare", Limit: int64(maxWords), Unit: limits.UnitWords})
+		return
+	}
+
+	// try to send task to compare workers within timeout or ignore
+	select {
+	case r.Compares <- &input:
+		c.JSON(http.StatusOK, gin.H{"message": "Success"})
+	case <-time.After(5 * time.Second):
+		c.Header(ratelimit.RetryAfterHeader, "1")
+		c.JSON(http.StatusTooManyRequests, gin.H{"message": "Unable to send job within timeout"})
+	}
+}
+
+// handleTokenize split texts to words and stem them, used by receiver to index texts and parse search queries
+func (r *Routes) handleTokenize(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "POST /tokenize")
+	}()
+	var input models.JsonTokenizeInput
+	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
+		if limits.IsTooLarge(err) {
+			log.Error().Str("handler", "handle tokenize").Err(err).Msg("Request body is too large")
+			limits.WriteTooLarge(c, "Request body is too large", r.App.Config.Limits.MaxBodyBytes, limits.UnitBytes)
+			return
+		}
+		log.Error().Str("handler", "handle tokenize").Err(err).Msg("Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	output := models.JsonTokenizeOutput{Tokens: make([][]models.JsonToken, len(input.Texts))}
+	// stems of repeated words are computed once
+	stems := make(map[string]string)
+	for i, text := range input.Texts {
+		output.Tokens[i] = make([]models.JsonToken, 0)
+		for _, token := range tokenize.Tokenize(text) {
+			stemmed, ok := stems[token.Word]
+			if !ok {
+				stemmed = stem.Stem(token.Word)
+				stems[token.Word] = stemmed
+			}
+			output.Tokens[i] = append(output.Tokens[i], models.JsonToken{Word: token.Word, Stem: stemmed, Start: token.Start, End: token.End})
+		}
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+// handleDiff compute word and sentence diff of two versions of text and change of their metrics
+func (r *Routes) handleDiff(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "POST /diff")
+	}()
+	var input models.JsonDiffInput
+	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
+		if limits.IsTooLarge(err) {
+			log.Error().Str("handler", "handle diff").Err(err).Msg("Request body is too large")
+			limits.WriteTooLarge(c, "Request body is too large", r.App.Config.Limits.MaxBodyBytes, limits.UnitBytes)
+			return
+		}
+		log.Error().Str("handler", "handle diff").Err(err).Msg("Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if input.Left == "" || input.Right == "" {
+		log.Error().Str("handler", "handle diff").Msg("Text cannot be empty")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Text cannot be empty"})
+		return
+	}
+	for _, names := range [][]string{input.LeftNormalize, input.RightNormalize} {
+		if _, err := normalize.Parse(names); err != nil {
+			log.Error().Str("handler", "handle diff").Strs("normalize", names).Err(err).Msg("Invalid normalization")
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	maxWords := r.App.Config.Limits.DiffWords
+	if maxWords > 0 && (len(strings.Fields(input.Left)) > maxWords || len(strings.Fields(input.Right)) > maxWords) {
+		log.Error().Str("handler", "handle diff").Msg("Texts are too long to diff")
+		c.JSON(http.StatusRequestEntityTooLarge, limits.JsonLimitError{Error: "Texts are too long to diff", Limit: int64(maxWords), Unit: limits.UnitWords})
+		return
+	}
+
+	task := &DiffTask{Input: input, Result: make(chan *models.JsonDiff, 1)}
+	select {
+	case r.Diffs <- task:
+	case <-time.After(5 * time.Second):
+		c.Header(ratelimit.RetryAfterHeader, "1")
 		c.JSON(http.StatusTooManyRequests, gin.H{"message": "Unable to send job within timeout"})
+		return
+	}
+	select {
+	case result := <-task.Result:
+		c.JSON(http.StatusOK, result)
+	case <-c.Request.Context().Done():
+		log.Error().Str("handler", "handle diff").Err(c.Request.Context().Err()).Msg("Client is gone")
+	}
+}
+
+// handleKwic find occurrences of term in text or document blob with their contexts
+func (r *Routes) handleKwic(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "POST /kwic")
+	}()
+	var input models.JsonKwicInput
+	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
+		if limits.IsTooLarge(err) {
+			log.Error().Str("handler", "handle kwic").Err(err).Msg("Request body is too large")
+			limits.WriteTooLarge(c, "Request body is too large", r.App.Config.Limits.MaxBodyBytes, limits.UnitBytes)
+			return
+		}
+		log.Error().Str("handler", "handle kwic").Err(err).Msg("Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if input.Text == "" && input.BlobID == "" {
+		log.Error().Str("handler", "handle kwic").Msg("Text cannot be empty")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Text cannot be empty"})
+		return
+	}
+	terms := tokenize.Tokenize(input.Term)
+	if len(terms) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "term must be one word"})
+		return
+	}
+	if input.Window < 0 || input.Limit <= 0 || input.Offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "window, limit and offset must be positive"})
+		return
+	}
+
+	text := io.Reader(strings.NewReader(input.Text))
+	if input.BlobID != "" {
+		file, err := r.App.Blobs.Open(input.BlobID)
+		if err != nil {
+			log.Error().Str("handler", "handle kwic").Str("blob", input.BlobID).Err(err).Msg("Failed to open document")
+			c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+			return
+		}
+		defer file.Close()
+		text = file
+	}
+	output, err := kwic.Find(text, r.App.Config.Limits.ChunkBytes, kwic.Query{
+		Term:   terms[0].Word,
+		Stems:  input.Stems,
+		Window: input.Window,
+		Limit:  input.Limit,
+		Offset: input.Offset,
+	})
+	if err == split.ErrWordTooLong {
+		log.Error().Str("handler", "handle kwic").Err(err).Msg("Invalid text")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Error().Str("handler", "handle kwic").Err(err).Msg("Failed to read text")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read text"})
+		return
+	}
+	c.JSON(http.StatusOK, output)
+}
+
+// handleEvict drop cached analyze of text, used by receiver on request erasure
+func (r *Routes) handleEvict(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "POST /cache/evict")
+	}()
+	var input models.JsonEvictInput
+	if err := c.ShouldBindBodyWithJSON(&input); err != nil {
+		if limits.IsTooLarge(err) {
+			limits.WriteTooLarge(c, "Request body is too large", r.App.Config.Limits.MaxBodyBytes, limits.UnitBytes)
+			return
+		}
+		log.Error().Str("handler", "handle evict").Err(err).Msg("Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	steps, err := normalize.Parse(input.Normalize)
+	if err != nil {
+		log.Error().Str("handler", "handle evict").Strs("normalize", input.Normalize).Err(err).Msg("Invalid normalization")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := r.App.Cache.Delete(input.Text, steps); err != nil {
+		log.Error().Str("handler", "handle evict").Err(err).Msg("redis delete error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to evict cache"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// handleFlush drop cached analyzes of analyzer version
+func (r *Routes) handleFlush(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "DELETE /cache/:version")
+	}()
+	deleted, err := r.App.Cache.FlushVersion(c.Param("version"))
+	if err != nil {
+		if err == cache.ErrInvalidVersion {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+			return
+		}
+		log.Error().Str("handler", "handle flush").Err(err).Msg("redis flush error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to flush cache", "deleted": deleted})
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "deleted": deleted})
 }
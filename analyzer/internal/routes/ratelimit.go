@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/Critma/textAnalyzer/shared/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// buckets limit request per client ip and globally
+func (r *Routes) buckets(c *gin.Context) []ratelimit.Bucket {
+	cfg := r.App.Config.RateLimit
+	return []ratelimit.Bucket{
+		{Scope: "client", Key: "ip:" + c.ClientIP(), Limit: cfg.Client},
+		{Scope: "global", Key: "global", Limit: cfg.Global},
+	}
+}
@@ -2,20 +2,32 @@ package routes
 
 import (
 	"github.com/Critma/textAnalyzer/analyzer/internal/config"
+	"github.com/Critma/textAnalyzer/analyzer/internal/metrics"
 	"github.com/Critma/textAnalyzer/analyzer/internal/models"
+	"github.com/Critma/textAnalyzer/shared/limits"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type Routes struct {
-	App  config.Application
-	Jobs chan *models.JsonInput
+	App      config.Application
+	Jobs     chan *models.JsonInput
+	Compares chan *models.JsonCompareInput
+	Diffs    chan *DiffTask
 }
 
-func New(app config.Application, jobs chan *models.JsonInput) Routes {
+// DiffTask is computed by diff workers while handler waits, Result must be buffered
+type DiffTask struct {
+	Input  models.JsonDiffInput
+	Result chan *models.JsonDiff
+}
+
+func New(app config.Application, jobs chan *models.JsonInput, compares chan *models.JsonCompareInput, diffs chan *DiffTask) Routes {
 	return Routes{
-		App:  app,
-		Jobs: jobs,
+		App:      app,
+		Jobs:     jobs,
+		Compares: compares,
+		Diffs:    diffs,
 	}
 }
 
@@ -27,7 +39,23 @@ func (r *Routes) Mount(rg *gin.Engine) {
 	router := rg.Group("/api")
 	router = router.Group("/v1")
 	{
-		router.POST("/analyze", r.handleAnalyze)
 		router.GET("/health", r.healthCheck)
 	}
+
+	// rate limited per client ip and globally, every request is signed with INTERNAL_SECRET by receiver or operator
+	api := router.Group("",
+		r.App.Limiter.Middleware(metrics.ObserveRateLimit, r.buckets),
+		limits.Body(r.App.Config.Limits.MaxBodyBytes),
+		r.App.Verifier.Middleware(r.App.Config.Limits.MaxBodyBytes),
+	)
+	{
+		api.POST("/analyze", r.handleAnalyze)
+		api.POST("/compare", r.handleCompare)
+		api.POST("/tokenize", r.handleTokenize)
+		api.POST("/kwic", r.handleKwic)
+		api.POST("/diff", r.handleDiff)
+		api.GET("/version", r.version)
+		api.POST("/cache/evict", r.handleEvict)
+		api.DELETE("/cache/:version", r.handleFlush)
+	}
 }
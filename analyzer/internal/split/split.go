@@ -0,0 +1,54 @@
+package split
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxWordBytes is the longest run of text without whitespace accepted in a chunk
+const MaxWordBytes = 1 << 20
+
+var ErrWordTooLong = errors.New("text has a word longer than 1MB")
+
+// NewScanner return scanner of text of r in chunks of at least chunkSize bytes (the last one may be shorter),
+// so large documents are not read in memory at once
+//
+// chunk is cut after its last whitespace, so no word is split,
+// scanning fails with ErrWordTooLong if there is no whitespace in chunkSize+MaxWordBytes bytes
+func NewScanner(r io.Reader, chunkSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, chunkSize), chunkSize+MaxWordBytes)
+	scanner.Split(Chunks(chunkSize))
+	return scanner
+}
+
+// Chunks return split function of NewScanner
+func Chunks(chunkSize int) bufio.SplitFunc {
+	limit := chunkSize + MaxWordBytes
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF {
+			if len(data) == 0 {
+				return 0, nil, nil
+			}
+			return len(data), data, nil
+		}
+		if len(data) < chunkSize {
+			return 0, nil, nil
+		}
+		cut := bytes.LastIndexFunc(data, unicode.IsSpace)
+		if cut < 0 {
+			if len(data) >= limit {
+				return 0, nil, ErrWordTooLong
+			}
+			// no whitespace, the word continues in the next read
+			return 0, nil, nil
+		}
+		// keep the whitespace in this chunk
+		_, size := utf8.DecodeRune(data[cut:])
+		return cut + size, data[:cut+size], nil
+	}
+}
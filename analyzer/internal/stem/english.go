@@ -0,0 +1,223 @@
+package stem
+
+import "strings"
+
+// englishExceptions are stemmed irregularly or kept as is
+var englishExceptions = map[string]string{
+	"skis": "ski", "skies": "sky", "dying": "die", "lying": "lie", "tying": "tie",
+	"idly": "idl", "gently": "gentl", "ugly": "ugli", "early": "earli", "only": "onli", "singly": "singl",
+	"sky": "sky", "news": "news", "howe": "howe", "atlas": "atlas", "cosmos": "cosmos", "bias": "bias", "andes": "andes",
+}
+
+// englishInvariants are kept as they are after step 1a
+var englishInvariants = map[string]bool{
+	"inning": true, "outing": true, "canning": true, "herring": true,
+	"earring": true, "proceed": true, "exceed": true, "succeed": true,
+}
+
+// suffix replacements of steps 2 and 3, the longest matching suffix is replaced
+var (
+	step2 = map[string]string{
+		"tional": "tion", "enci": "ence", "anci": "ance", "abli": "able", "entli": "ent",
+		"izer": "ize", "ization": "ize", "ational": "ate", "ation": "ate", "ator": "ate",
+		"alism": "al", "aliti": "al", "alli": "al", "fulness": "ful", "ousli": "ous", "ousness": "ous",
+		"iveness": "ive", "iviti": "ive", "biliti": "ble", "bli": "ble", "fulli": "ful", "lessli": "less",
+		// ogi and li are replaced on conditions
+		"ogi": "og", "li": "",
+	}
+	step3 = map[string]string{
+		"tional": "tion", "ational": "ate", "alize": "al", "icate": "ic", "iciti": "ic", "ical": "ic",
+		"ful": "", "ness": "",
+		// ative is removed in R2 only
+		"ative": "",
+	}
+	step4 = []string{
+		"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment", "ent",
+		"ism", "ate", "iti", "ous", "ive", "ize", "ion",
+	}
+)
+
+// english is a Snowball English (Porter2) stemmer
+//
+// see https://snowballstem.org/algorithms/english/stemmer.html
+func english(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	if stem, ok := englishExceptions[word]; ok {
+		return stem
+	}
+	w := []byte(strings.TrimPrefix(word, "'"))
+	// y is a consonant at the start and after a vowel
+	for i := range w {
+		if w[i] == 'y' && (i == 0 || isEnglishVowel(w[i-1])) {
+			w[i] = 'Y'
+		}
+	}
+	r1, r2 := englishRegions(w)
+
+	// step 0
+	for _, s := range []string{"'s'", "'s", "'"} {
+		if hasSuffix(w, s) {
+			w = w[:len(w)-len(s)]
+			break
+		}
+	}
+	// step 1a
+	switch {
+	case hasSuffix(w, "sses"):
+		w = w[:len(w)-2]
+	case hasSuffix(w, "ied"), hasSuffix(w, "ies"):
+		if len(w) > 4 {
+			w = w[:len(w)-2]
+		} else {
+			w = w[:len(w)-1]
+		}
+	case hasSuffix(w, "us"), hasSuffix(w, "ss"):
+	case hasSuffix(w, "s"):
+		if containsVowel(w[:max(len(w)-2, 0)]) {
+			w = w[:len(w)-1]
+		}
+	}
+	if englishInvariants[string(w)] {
+		return string(w)
+	}
+	// step 1b
+	if s := longestSuffix(w, "eedly", "eed"); s != "" {
+		if len(w)-len(s) >= r1 {
+			w = append(w[:len(w)-len(s)], "ee"...)
+		}
+	} else if s := longestSuffix(w, "ingly", "edly", "ing", "ed"); s != "" && containsVowel(w[:len(w)-len(s)]) {
+		w = w[:len(w)-len(s)]
+		switch {
+		case hasSuffix(w, "at"), hasSuffix(w, "bl"), hasSuffix(w, "iz"):
+			w = append(w, 'e')
+		case endsWithDouble(w):
+			w = w[:len(w)-1]
+		case isShortWord(w, r1):
+			w = append(w, 'e')
+		}
+	}
+	// step 1c
+	if n := len(w); n > 2 && (w[n-1] == 'y' || w[n-1] == 'Y') && !isEnglishVowel(w[n-2]) {
+		w[n-1] = 'i'
+	}
+	// step 2
+	if s := longestSuffix(w, keys(step2)...); s != "" && len(w)-len(s) >= r1 {
+		base := w[:len(w)-len(s)]
+		switch s {
+		case "ogi":
+			if hasSuffix(base, "l") {
+				w = append(base, "og"...)
+			}
+		case "li":
+			if len(base) > 0 && strings.IndexByte("cdeghkmnrt", base[len(base)-1]) >= 0 {
+				w = base
+			}
+		default:
+			w = append(base, step2[s]...)
+		}
+	}
+	// step 3
+	if s := longestSuffix(w, keys(step3)...); s != "" && len(w)-len(s) >= r1 {
+		if s != "ative" || len(w)-len(s) >= r2 {
+			w = append(w[:len(w)-len(s)], step3[s]...)
+		}
+	}
+	// step 4
+	if s := longestSuffix(w, step4...); s != "" && len(w)-len(s) >= r2 {
+		base := w[:len(w)-len(s)]
+		if s != "ion" || hasSuffix(base, "s") || hasSuffix(base, "t") {
+			w = base
+		}
+	}
+	// step 5
+	switch n := len(w); {
+	case n > 0 && w[n-1] == 'e' && (n-1 >= r2 || n-1 >= r1 && !endsWithShortSyllable(w[:n-1])):
+		w = w[:n-1]
+	case n > 1 && w[n-1] == 'l' && w[n-2] == 'l' && n-1 >= r2:
+		w = w[:n-1]
+	}
+	return strings.ToLower(string(w))
+}
+
+// englishRegions return R1 and R2 of word
+func englishRegions(w []byte) (int, int) {
+	r1 := -1
+	for _, prefix := range []string{"gener", "commun", "arsen"} {
+		if strings.HasPrefix(string(w), prefix) {
+			r1 = len(prefix)
+		}
+	}
+	if r1 < 0 {
+		r1 = englishRegion(w, 0)
+	}
+	return r1, englishRegion(w, r1)
+}
+
+// englishRegion return position after the first non-vowel following a vowel in w[start:]
+func englishRegion(w []byte, start int) int {
+	for i := start + 1; i < len(w); i++ {
+		if !isEnglishVowel(w[i]) && isEnglishVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// longestSuffix return the longest of suffixes w ends with, or empty string
+func longestSuffix(w []byte, suffixes ...string) string {
+	longest := ""
+	for _, s := range suffixes {
+		if len(s) > len(longest) && hasSuffix(w, s) {
+			longest = s
+		}
+	}
+	return longest
+}
+
+func keys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	return result
+}
+
+func hasSuffix(w []byte, s string) bool {
+	return strings.HasSuffix(string(w), s)
+}
+
+func containsVowel(w []byte) bool {
+	for _, c := range w {
+		if isEnglishVowel(c) {
+			return true
+		}
+	}
+	return false
+}
+
+func endsWithDouble(w []byte) bool {
+	n := len(w)
+	return n > 1 && w[n-1] == w[n-2] && strings.IndexByte("bdfgmnprt", w[n-1]) >= 0
+}
+
+// endsWithShortSyllable report whether w ends with a vowel followed by a non-vowel other than w, x, Y
+// and preceded by a non-vowel, or w is a vowel followed by a non-vowel
+func endsWithShortSyllable(w []byte) bool {
+	n := len(w)
+	if n == 2 {
+		return isEnglishVowel(w[0]) && !isEnglishVowel(w[1])
+	}
+	return n > 2 && !isEnglishVowel(w[n-3]) && isEnglishVowel(w[n-2]) &&
+		!isEnglishVowel(w[n-1]) && strings.IndexByte("wxY", w[n-1]) < 0
+}
+
+// isShortWord report whether R1 is empty and w ends with a short syllable
+func isShortWord(w []byte, r1 int) bool {
+	return r1 >= len(w) && endsWithShortSyllable(w)
+}
+
+func isEnglishVowel(c byte) bool {
+	return strings.IndexByte("aeiouy", c) >= 0
+}
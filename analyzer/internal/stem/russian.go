@@ -0,0 +1,127 @@
+package stem
+
+import "strings"
+
+// ending is a suffix of Russian stemmer, afterAYa ending is removed only after а or я
+type ending struct {
+	suffix   []rune
+	afterAYa bool
+}
+
+func endings(afterAYa []string, plain ...string) []ending {
+	var result []ending
+	for _, s := range afterAYa {
+		result = append(result, ending{suffix: []rune(s), afterAYa: true})
+	}
+	for _, s := range plain {
+		result = append(result, ending{suffix: []rune(s)})
+	}
+	return result
+}
+
+var (
+	perfectiveGerund = endings([]string{"в", "вши", "вшись"}, "ив", "ивши", "ившись", "ыв", "ывши", "ывшись")
+	adjective        = endings(nil, "ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею")
+	participle = endings([]string{"ем", "нн", "вш", "ющ", "щ"}, "ивш", "ывш", "ующ")
+	reflexive  = endings(nil, "ся", "сь")
+	verb       = endings([]string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"},
+		"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен",
+		"ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю")
+	noun = endings(nil, "а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й",
+		"иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я")
+	superlative   = endings(nil, "ейше", "ейш")
+	derivational  = endings(nil, "ость", "ост")
+	russianVowels = "аеиоуыэюя"
+)
+
+// russian is a Snowball Russian stemmer
+//
+// see https://snowballstem.org/algorithms/russian/stemmer.html
+func russian(word string) string {
+	w := []rune(strings.ReplaceAll(word, "ё", "е"))
+	rv := len(w)
+	for i, r := range w {
+		if isRussianVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	r2 := russianRegion(w, russianRegion(w, 0))
+
+	// step 1
+	if n, ok := removable(w, rv, perfectiveGerund); ok {
+		w = w[:len(w)-n]
+	} else {
+		if n, ok := removable(w, rv, reflexive); ok {
+			w = w[:len(w)-n]
+		}
+		if n, ok := removable(w, rv, adjective); ok {
+			w = w[:len(w)-n]
+			if n, ok := removable(w, rv, participle); ok {
+				w = w[:len(w)-n]
+			}
+		} else if n, ok := removable(w, rv, verb); ok {
+			w = w[:len(w)-n]
+		} else if n, ok := removable(w, rv, noun); ok {
+			w = w[:len(w)-n]
+		}
+	}
+	// step 2
+	if len(w) > rv && w[len(w)-1] == 'и' {
+		w = w[:len(w)-1]
+	}
+	// step 3
+	if n, ok := removable(w, max(rv, r2), derivational); ok {
+		w = w[:len(w)-n]
+	}
+	// step 4
+	n, found := removable(w, rv, superlative)
+	w = w[:len(w)-n]
+	switch {
+	case len(w)-2 >= rv && w[len(w)-1] == 'н' && w[len(w)-2] == 'н':
+		w = w[:len(w)-1]
+	case !found && len(w) > rv && w[len(w)-1] == 'ь':
+		w = w[:len(w)-1]
+	}
+	return string(w)
+}
+
+// removable return length of the longest ending of w within w[start:],
+// ok is false if there is no such ending or it must follow а or я and does not
+func removable(w []rune, start int, list []ending) (int, bool) {
+	var longest *ending
+	for i, e := range list {
+		n := len(e.suffix)
+		if len(w)-n < start || (longest != nil && n <= len(longest.suffix)) {
+			continue
+		}
+		if string(w[len(w)-n:]) == string(e.suffix) {
+			longest = &list[i]
+		}
+	}
+	if longest == nil {
+		return 0, false
+	}
+	n := len(longest.suffix)
+	if longest.afterAYa {
+		if len(w)-n-1 < start || (w[len(w)-n-1] != 'а' && w[len(w)-n-1] != 'я') {
+			return 0, false
+		}
+	}
+	return n, true
+}
+
+// russianRegion return position after the first non-vowel following a vowel in w[start:]
+func russianRegion(w []rune, start int) int {
+	for i := start + 1; i < len(w); i++ {
+		if !isRussianVowel(w[i]) && isRussianVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+func isRussianVowel(r rune) bool {
+	return strings.ContainsRune(russianVowels, r)
+}
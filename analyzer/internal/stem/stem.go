@@ -0,0 +1,30 @@
+package stem
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Stem return stem of lower-cased word, language is chosen by script of the word:
+// Cyrillic words are stemmed as Russian, Latin ones as English, other words are kept as is
+func Stem(word string) string {
+	cyrillic, latin := false, false
+	for _, r := range word {
+		switch {
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic = true
+		case r <= unicode.MaxASCII && unicode.IsLetter(r):
+			latin = true
+		case r == '\'' || r == '’' || unicode.IsDigit(r):
+		default:
+			return word
+		}
+	}
+	switch {
+	case cyrillic && !latin:
+		return russian(word)
+	case latin && !cyrillic:
+		return english(strings.ReplaceAll(word, "’", "'"))
+	}
+	return word
+}
@@ -0,0 +1,46 @@
+package tokenize
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a lower-cased word without surrounding punctuation,
+// Start and End are byte offsets of the word in text
+type Token struct {
+	Word       string
+	Start, End int
+}
+
+// Tokenize split text at whitespace and trim punctuation, the same way words are counted by analysis
+func Tokenize(text string) []Token {
+	var tokens []Token
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+		end := i + strings.IndexFunc(text[i:], unicode.IsSpace)
+		if end < i {
+			end = len(text)
+		}
+		field := text[i:end]
+		if first := strings.IndexFunc(field, isWordRune); first >= 0 {
+			last := strings.LastIndexFunc(field, isWordRune)
+			_, size := utf8.DecodeRuneInString(field[last:])
+			tokens = append(tokens, Token{
+				Word:  strings.ToLower(field[first : last+size]),
+				Start: i + first,
+				End:   i + last + size,
+			})
+		}
+		i = end
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"io"
 	"math"
-	"time"
 
 	"net/http"
 	"testing"
@@ -97,11 +96,8 @@ func TestEndToEndFlow(t *testing.T) {
 				t.Fatalf("cannot decode response: %v", err)
 			}
 
-			// wait until processed
-			time.Sleep(2 * time.Second)
-
-			// check analyze result
-			getEndpoint := fmt.Sprintf(receiverHost+"/api/v1/status/%s", response.ID)
+			// check analyze result, long polling until processed
+			getEndpoint := fmt.Sprintf(receiverHost+"/api/v1/status/%s?wait=10s", response.ID)
 			reqGet, err := http.NewRequest("GET", getEndpoint, nil)
 			if err != nil {
 				t.Fatalf("can't create GET request: %v", err)
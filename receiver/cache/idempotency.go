@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const idempotencyObj = "idempotency"
+
+// idempotencyPendingTTL of reserved key, so a key of crashed request is freed soon
+const idempotencyPendingTTL = 30 * time.Second
+
+// IdempotencyRecord bind idempotency key to request fingerprint and created request
+type IdempotencyRecord struct {
+	Fingerprint string
+	// RequestID is uuid.Nil while the first request is in progress
+	RequestID uuid.UUID
+}
+
+// ReserveIdempotencyKey store pending record on key for a short time if key is free,
+// ttl is extended when key is completed
+//
+// return stored record and false if key is already taken
+func ReserveIdempotencyKey(rdb *redis.Client, key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	record := IdempotencyRecord{Fingerprint: fingerprint}
+	val, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+	ok, err := rdb.SetNX(context.Background(), getIdempotencyKey(key), val, min(idempotencyPendingTTL, ttl)).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if ok {
+		return &record, true, nil
+	}
+
+	stored, err := rdb.Get(context.Background(), getIdempotencyKey(key)).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := json.Unmarshal([]byte(stored), &record); err != nil {
+		return nil, false, err
+	}
+	return &record, false, nil
+}
+
+// CompleteIdempotencyKey bind reserved key to created request for ttl
+func CompleteIdempotencyKey(rdb *redis.Client, key string, record IdempotencyRecord, ttl time.Duration) error {
+	val, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return rdb.SetArgs(context.Background(), getIdempotencyKey(key), val, redis.SetArgs{Mode: "XX", TTL: ttl}).Err()
+}
+
+// ReleaseIdempotencyKey free reserved key, so the request may be retried
+func ReleaseIdempotencyKey(rdb *redis.Client, key string) error {
+	return rdb.Del(context.Background(), getIdempotencyKey(key)).Err()
+}
+
+// getIdempotencyKey return idempotency:{key}
+func getIdempotencyKey(key string) string {
+	return fmt.Sprintf("%s:%s", idempotencyObj, key)
+}
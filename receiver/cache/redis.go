@@ -4,55 +4,88 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"receiver/internal/metrics"
 	"receiver/internal/storage"
 	"time"
 
-	"github.com/google/uuid"
+	"github.com/Critma/textAnalyzer/shared/tiered"
 	"github.com/redis/go-redis/v9"
-	"github.com/rs/zerolog/log"
 )
 
 const receiverObj = "receiver"
 
-var ttl time.Duration = 5 * time.Minute
+// Options of two-tier cache
+type Options = tiered.Options
 
-// GetFromRedis return pointer to TextRequest
-//
-// return nil if redis error or key not presented
-func GetFromRedis(rdb *redis.Client, id uuid.UUID) *storage.TextRequest {
-	var result storage.TextRequest
-	val, err := rdb.Get(context.Background(), getRedisKey(id.String())).Result()
+// RequestCache cache TextRequest by id
+type RequestCache = tiered.Cache[storage.TextRequest]
+
+// NewRequestCache return two-tier cache of TextRequest versioned by Revision, negative caching storage.ErrNotFound
+func NewRequestCache(rdb *redis.Client, opts Options) *RequestCache {
+	opts.Name = receiverObj
+	opts.Observe = metrics.ObserveCache
+	opts.NotFound = storage.ErrNotFound
+	return tiered.NewVersioned[storage.TextRequest](&redisRemote[storage.TextRequest]{rdb: rdb, obj: receiverObj}, opts,
+		func(request storage.TextRequest) uint64 { return request.Revision })
+}
+
+// setIfNewer set KEYS[1] to ARGV[1] with ttl ARGV[3] ms (0 - no expiration),
+// unless stored entry revision is greater than ARGV[2], return 0 then
+var setIfNewer = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	local ok, entry = pcall(cjson.decode, current)
+	if ok and type(entry) == 'table' and tonumber(entry['revision'] or 0) > tonumber(ARGV[2]) then
+		return 0
+	end
+end
+if tonumber(ARGV[3]) > 0 then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[3])
+else
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// redisRemote store json entries on keys {obj}:{key}
+type redisRemote[V any] struct {
+	rdb *redis.Client
+	obj string
+}
+
+func (r *redisRemote[V]) Get(ctx context.Context, key string) (tiered.Entry[V], error) {
+	var entry tiered.Entry[V]
+	val, err := r.rdb.Get(ctx, r.getRedisKey(key)).Result()
 	if err != nil {
-		if err != redis.Nil {
-			log.Error().Str("event", "get from redis").Str("requestID", id.String()).Err(err).Msg("Redis error")
-		}
-		return nil
-	} else {
-		err = json.Unmarshal([]byte(val), &result)
-		if err != nil {
-			log.Error().Str("event", "unmarshall redis value").Str("requestID", id.String()).Err(err).Msg("Failed to unmarshal")
-			return nil
+		if err == redis.Nil {
+			return entry, tiered.ErrMiss
 		}
+		return entry, err
 	}
-	return &result
+	err = json.Unmarshal([]byte(val), &entry)
+	return entry, err
 }
 
-// SetInRedis set TextRequest on key id
-func SetInRedis(result *storage.TextRequest, rdb *redis.Client, id uuid.UUID) {
-	redisVal, err := json.Marshal(result)
+func (r *redisRemote[V]) Set(ctx context.Context, key string, entry tiered.Entry[V], ttl time.Duration) error {
+	redisVal, err := json.Marshal(entry)
 	if err != nil {
-		log.Error().Str("event", "marshall value").Any("obj", *result).Err(err).Msg("Failed to marshal object")
-		return
+		return err
 	}
-	err = rdb.Set(context.Background(), getRedisKey(id.String()), string(redisVal), ttl).Err()
+	set, err := setIfNewer.Run(ctx, r.rdb, []string{r.getRedisKey(key)}, string(redisVal), entry.Revision, ttl.Milliseconds()).Int()
 	if err != nil {
-		log.Error().Str("event", "redis set").Any("obj", *result).Err(err).Msg("Failed to marshal object")
-		return
+		return err
+	}
+	if set == 0 {
+		return tiered.ErrStale
 	}
+	return nil
+}
+
+func (r *redisRemote[V]) Delete(ctx context.Context, key string) error {
+	return r.rdb.Del(ctx, r.getRedisKey(key)).Err()
 }
 
-// getRedisKey return {obj}:{id}
-func getRedisKey(id string) string {
-	key := fmt.Sprintf("%s:%s", receiverObj, id)
-	return key
+// getRedisKey return {obj}:{key}
+func (r *redisRemote[V]) getRedisKey(key string) string {
+	return fmt.Sprintf("%s:%s", r.obj, key)
 }
@@ -2,14 +2,28 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"net/http"
+	"os"
 	"os/signal"
+	"receiver/cache"
+	"receiver/internal/auth"
+	"receiver/internal/blob"
 	"receiver/internal/config"
+	"receiver/internal/events"
+	"receiver/internal/retention"
+	"receiver/internal/search"
+	"receiver/internal/similarity"
 	"receiver/internal/storage/local"
+	"receiver/internal/webhook"
 	"receiver/routes"
 	"syscall"
 	"time"
 
+	"github.com/Critma/textAnalyzer/shared/ratelimit"
+	"github.com/Critma/textAnalyzer/shared/signature"
 	"github.com/gin-gonic/gin"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
@@ -43,17 +57,51 @@ func main() {
 	})
 	defer redisClient.Close()
 
+	requestCache := cache.NewRequestCache(redisClient, cache.Options{
+		LocalSize:   cfg.Cache.LocalSize,
+		LocalTTL:    cfg.Cache.LocalTTL,
+		TTL:         cfg.Cache.TTL,
+		NegativeTTL: cfg.Cache.NegativeTTL,
+	})
+
+	blobs, err := blob.New(cfg.BlobDir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to open blob store")
+	}
+
+	store := local.New()
 	app := config.Application{
 		Config:     cfg,
-		Store:      local.New(),
+		Store:      store,
 		Redis:      redisClient,
+		Cache:      requestCache,
 		HttpClient: &http.Client{Timeout: 5 * time.Second},
+		Events:     events.NewHub(redisClient),
+		Keys:       auth.NewKeys(redisClient),
+		Verifier:   signature.NewVerifier(redisClient, cfg.Internal.Secret, cfg.Internal.MaxSkew),
+		Limiter:    ratelimit.New(redisClient, "receiver"),
+		Blobs:      blobs,
+		Similar:    similarity.New(cfg.Similar.Bands),
+		Search:     search.New(&http.Client{Timeout: 10 * time.Second}, cfg.AnalyzerAddr, cfg.Internal.Secret),
+		Webhooks:   webhook.New(store, webhook.NewClient(10*time.Second), cfg.Webhook.Secret, cfg.Webhook.MaxAttempts, cfg.Webhook.Backoff),
 	}
 
 	r := gin.Default()
 	routes := routes.New(app)
 	routes.Mount(r)
 
+	// internal endpoints listen on their own port, not published outside
+	var internalServer *http.Server
+	if cfg.Internal.Addr != "" {
+		internalRouter := gin.Default()
+		routes.MountInternal(internalRouter)
+		if internalServer, err = newInternalServer(cfg.Internal, internalRouter); err != nil {
+			log.Fatal().Err(err).Msg("failed to configure internal listener")
+		}
+	} else {
+		log.Warn().Msg("INTERNAL_ADDR is not set, internal endpoints are served on the public listener")
+	}
+
 	// Graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
@@ -63,6 +111,18 @@ func main() {
 		Handler: r,
 	}
 
+	// purge expired texts
+	go retention.Run(ctx, app)
+	// deliver callbacks
+	go app.Webhooks.Run(ctx)
+	// index texts for search
+	go app.Search.Run(ctx)
+	// receive status events of all replicas, drop local copies changed by another replica
+	app.Events.Listen(func(event events.StatusEvent) {
+		app.Cache.Invalidate(event.ID.String(), event.Revision)
+	})
+	go app.Events.Run(ctx)
+
 	go func() {
 		// run server in goroutine
 		err := server.ListenAndServe()
@@ -70,6 +130,19 @@ func main() {
 			panic(err)
 		}
 	}()
+	if internalServer != nil {
+		go func() {
+			var err error
+			if cfg.Internal.TLSCert != "" {
+				err = internalServer.ListenAndServeTLS(cfg.Internal.TLSCert, cfg.Internal.TLSKey)
+			} else {
+				err = internalServer.ListenAndServe()
+			}
+			if err != nil && err != http.ErrServerClosed {
+				panic(err)
+			}
+		}()
+	}
 
 	// Wait for interrupt signal
 	<-ctx.Done()
@@ -81,6 +154,36 @@ func main() {
 	if err := server.Shutdown(ctxTimeout); err != nil {
 		panic(err)
 	}
+	if internalServer != nil {
+		if err := internalServer.Shutdown(ctxTimeout); err != nil {
+			panic(err)
+		}
+	}
 
 	log.Info().Msg("Server stopped...")
 }
+
+// newInternalServer return server of internal endpoints, with client certificate verification if ClientCA is set
+func newInternalServer(cfg config.Internal, handler http.Handler) (*http.Server, error) {
+	server := &http.Server{
+		Addr:    cfg.Addr,
+		Handler: handler,
+	}
+	if cfg.TLSClientCA == "" {
+		return server, nil
+	}
+	pem, err := os.ReadFile(cfg.TLSClientCA)
+	if err != nil {
+		return nil, err
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates in " + cfg.TLSClientCA)
+	}
+	server.TLSConfig = &tls.Config{
+		ClientCAs:  clientCAs,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		MinVersion: tls.VersionTLS12,
+	}
+	return server, nil
+}
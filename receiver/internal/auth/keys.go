@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	keyObj = "apikey"
+	// keyPrefix mark api keys, so leaked ones are easy to find
+	keyPrefix = "ta_"
+)
+
+var (
+	ErrInvalidKey  = errors.New("invalid api key")
+	ErrKeyNotFound = errors.New("api key not found")
+	ErrEmptyTenant = errors.New("empty tenant")
+)
+
+// APIKey is a client credential, only SHA-256 of the key itself is stored
+type APIKey struct {
+	ID     uuid.UUID `json:"id"`
+	Tenant string    `json:"tenant"`
+	Name   string    `json:"name"`
+	// DailyQuota is a number of characters submitted per UTC day, 0 is unlimited
+	DailyQuota int       `json:"dailyQuota"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Keys store api keys in redis: apikey:{sha256 of key} hold APIKey, apikey:id:{id} hold the hash
+type Keys struct {
+	rdb *redis.Client
+}
+
+func NewKeys(rdb *redis.Client) *Keys {
+	return &Keys{rdb: rdb}
+}
+
+// Create issue new key of tenant, return the key itself, it is never stored or shown again
+func (k *Keys) Create(ctx context.Context, tenant, name string, dailyQuota int) (string, *APIKey, error) {
+	if tenant == "" {
+		return "", nil, ErrEmptyTenant
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	plain := keyPrefix + hex.EncodeToString(secret)
+	key := &APIKey{
+		ID:         uuid.New(),
+		Tenant:     tenant,
+		Name:       name,
+		DailyQuota: max(dailyQuota, 0),
+		CreatedAt:  time.Now().UTC(),
+	}
+	data, err := json.Marshal(key)
+	if err != nil {
+		return "", nil, err
+	}
+
+	hash := hashKey(plain)
+	pipe := k.rdb.TxPipeline()
+	pipe.Set(ctx, hashRedisKey(hash), data, 0)
+	pipe.Set(ctx, idRedisKey(key.ID), hash, 0)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", nil, err
+	}
+	return plain, key, nil
+}
+
+// Lookup return key data of plain key, ErrInvalidKey if key is unknown or revoked
+func (k *Keys) Lookup(ctx context.Context, plain string) (*APIKey, error) {
+	val, err := k.rdb.Get(ctx, hashRedisKey(hashKey(plain))).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+	var key APIKey
+	if err := json.Unmarshal([]byte(val), &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Revoke delete key by id, ErrKeyNotFound if there is no such key
+func (k *Keys) Revoke(ctx context.Context, id uuid.UUID) error {
+	hash, err := k.rdb.Get(ctx, idRedisKey(id)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return ErrKeyNotFound
+		}
+		return err
+	}
+	return k.rdb.Del(ctx, hashRedisKey(hash), idRedisKey(id), quotaRedisKey(id, time.Now())).Err()
+}
+
+// hashKey return hex SHA-256 of plain key
+//
+// keys are random 256 bit, so a fast hash without salt is enough
+func hashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashRedisKey return apikey:{hash}
+func hashRedisKey(hash string) string {
+	return fmt.Sprintf("%s:%s", keyObj, hash)
+}
+
+// idRedisKey return apikey:id:{id}
+func idRedisKey(id uuid.UUID) string {
+	return fmt.Sprintf("%s:id:%s", keyObj, id)
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const quotaObj = "quota"
+
+// quotaTTL keep daily counter until the day is over in every timezone
+const quotaTTL = 48 * time.Hour
+
+var ErrQuotaExceeded = errors.New("daily quota exceeded")
+
+// consumeQuota add ARGV[1] to counter KEYS[1] unless it exceed limit ARGV[2],
+// return {1 if added, remaining}
+var consumeQuota = redis.NewScript(`
+local used = tonumber(redis.call('GET', KEYS[1]) or '0')
+local limit = tonumber(ARGV[2])
+local chars = tonumber(ARGV[1])
+if used + chars > limit then
+	return {0, limit - used}
+end
+used = redis.call('INCRBY', KEYS[1], chars)
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return {1, limit - used}
+`)
+
+// Consume charge chars to today's quota of key, return remaining characters
+//
+// ErrQuotaExceeded is returned and nothing is charged if chars do not fit, remaining is -1 for unlimited keys
+func (k *Keys) Consume(ctx context.Context, key *APIKey, chars int) (int, error) {
+	if key.DailyQuota <= 0 {
+		return -1, nil
+	}
+	result, err := consumeQuota.Run(ctx, k.rdb, []string{quotaRedisKey(key.ID, time.Now())},
+		chars, key.DailyQuota, int(quotaTTL.Seconds())).Int64Slice()
+	if err != nil {
+		return 0, err
+	}
+	remaining := max(int(result[1]), 0)
+	if result[0] == 0 {
+		return remaining, ErrQuotaExceeded
+	}
+	return remaining, nil
+}
+
+// refundQuota subtract ARGV[1] from counter KEYS[1] if it exists, not below zero,
+// return the counter
+var refundQuota = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local used = redis.call('DECRBY', KEYS[1], ARGV[1])
+if used < 0 then
+	redis.call('SET', KEYS[1], 0, 'KEEPTTL')
+	used = 0
+end
+return used
+`)
+
+// Refund return chars charged by Consume to today's quota of key, when the charged work is not done,
+// return remaining characters, -1 for unlimited keys
+func (k *Keys) Refund(ctx context.Context, key *APIKey, chars int) (int, error) {
+	if key.DailyQuota <= 0 {
+		return -1, nil
+	}
+	used, err := refundQuota.Run(ctx, k.rdb, []string{quotaRedisKey(key.ID, time.Now())}, chars).Int()
+	if err != nil {
+		return 0, err
+	}
+	return max(key.DailyQuota-used, 0), nil
+}
+
+// Remaining return characters left in today's quota of key, -1 for unlimited keys
+func (k *Keys) Remaining(ctx context.Context, key *APIKey) (int, error) {
+	if key.DailyQuota <= 0 {
+		return -1, nil
+	}
+	used, err := k.rdb.Get(ctx, quotaRedisKey(key.ID, time.Now())).Int()
+	if err != nil && err != redis.Nil {
+		return 0, err
+	}
+	return max(key.DailyQuota-used, 0), nil
+}
+
+// quotaRedisKey return quota:{id}:{UTC date}
+func quotaRedisKey(id uuid.UUID, now time.Time) string {
+	return fmt.Sprintf("%s:%s:%s", quotaObj, id, now.UTC().Format(time.DateOnly))
+}
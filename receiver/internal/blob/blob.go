@@ -0,0 +1,114 @@
+package blob
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmpty     = errors.New("empty blob")
+	ErrInvalidID = errors.New("invalid blob id")
+)
+
+// Blob is a stored document
+type Blob struct {
+	ID   string
+	Size int64
+	// Chars is a number of utf-8 characters
+	Chars int
+	// ContentHash is hex SHA-256 of content
+	ContentHash string
+}
+
+// Store keep large documents as files in a directory shared with analyzer
+type Store struct {
+	dir string
+}
+
+// New return store in dir, dir is created if missing
+func New(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir}, nil
+}
+
+// Put stream r to a new blob, content is hashed and counted on the fly
+//
+// a partially written blob is removed on error
+func (s *Store) Put(r io.Reader) (*Blob, error) {
+	file, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(file.Name())
+
+	hash := sha256.New()
+	counter := &charCounter{}
+	size, err := io.Copy(file, io.TeeReader(r, io.MultiWriter(hash, counter)))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if size == 0 {
+		return nil, ErrEmpty
+	}
+
+	blob := &Blob{ID: uuid.NewString(), Size: size, Chars: counter.chars, ContentHash: hex.EncodeToString(hash.Sum(nil))}
+	if err := os.Rename(file.Name(), s.path(blob.ID)); err != nil {
+		return nil, err
+	}
+	return blob, nil
+}
+
+// Open return reader of blob content
+func (s *Store) Open(id string) (*os.File, error) {
+	if !validID(id) {
+		return nil, ErrInvalidID
+	}
+	return os.Open(s.path(id))
+}
+
+// Delete remove blob, missing blob is not an error
+func (s *Store) Delete(id string) error {
+	if !validID(id) {
+		return ErrInvalidID
+	}
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+// validID report whether id is a canonical uuid, so it can't point outside of store dir
+func validID(id string) bool {
+	parsed, err := uuid.Parse(id)
+	return err == nil && parsed.String() == id
+}
+
+// charCounter count utf-8 characters of written bytes, split characters are counted once
+type charCounter struct {
+	chars int
+}
+
+func (c *charCounter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		// every byte except continuation bytes start a character
+		if b&0xC0 != 0x80 {
+			c.chars++
+		}
+	}
+	return len(p), nil
+}
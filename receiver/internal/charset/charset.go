@@ -0,0 +1,265 @@
+package charset
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	xunicode "golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// Encoding of source text
+type Encoding string
+
+var (
+	UTF8        Encoding = "utf-8"
+	UTF16LE     Encoding = "utf-16le"
+	UTF16BE     Encoding = "utf-16be"
+	Windows1251 Encoding = "windows-1251"
+	KOI8R       Encoding = "koi8-r"
+)
+
+var ErrInvalidUTF8 = errors.New("text is not valid utf-8 and its encoding is not recognized")
+
+// sampleSize is a number of bytes encoding is detected by
+const sampleSize = 64 << 10
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// frequentLetters are the most frequent letters of russian texts
+const frequentLetters = "оеаинтсрвлкмдпу"
+
+// Detect return encoding of text by its beginning, empty if it is not recognized
+//
+// BOM is checked first, then UTF-16 by zero (or Cyrillic high) bytes of every second position,
+// then UTF-8 validity, then single-byte Cyrillic encodings by letter frequencies
+func Detect(sample []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(sample, bomUTF8):
+		return UTF8
+	case bytes.HasPrefix(sample, bomUTF16LE):
+		return UTF16LE
+	case bytes.HasPrefix(sample, bomUTF16BE):
+		return UTF16BE
+	}
+	if encoding := detectUTF16(sample); encoding != "" {
+		return encoding
+	}
+	if validPrefix(sample) {
+		return UTF8
+	}
+	return detectCyrillic(sample)
+}
+
+// Decode return text as UTF-8 string and its detected encoding
+func Decode(data []byte) (string, Encoding, error) {
+	reader, encoding, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", "", err
+	}
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return "", "", err
+	}
+	return string(decoded), encoding, nil
+}
+
+// NewReader detect encoding of r and return reader of its text transcoded to UTF-8 without BOM
+//
+// reading text that is not valid in detected encoding is ErrInvalidUTF8
+func NewReader(r io.Reader) (io.Reader, Encoding, error) {
+	buffered := bufio.NewReaderSize(r, sampleSize)
+	sample, err := buffered.Peek(sampleSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, "", err
+	}
+	encoding := Detect(sample)
+	if encoding == "" {
+		return nil, "", ErrInvalidUTF8
+	}
+	if encoding == UTF8 && bytes.HasPrefix(sample, bomUTF8) {
+		buffered.Discard(len(bomUTF8))
+	}
+	var decoded io.Reader = buffered
+	if decoder := decoderOf(encoding); decoder != nil {
+		decoded = transform.NewReader(buffered, decoder)
+	}
+	return &validReader{r: decoded}, encoding, nil
+}
+
+func decoderOf(encoding Encoding) *encoding.Decoder {
+	switch encoding {
+	case UTF16LE:
+		return xunicode.UTF16(xunicode.LittleEndian, xunicode.UseBOM).NewDecoder()
+	case UTF16BE:
+		return xunicode.UTF16(xunicode.BigEndian, xunicode.UseBOM).NewDecoder()
+	case Windows1251:
+		return charmap.Windows1251.NewDecoder()
+	case KOI8R:
+		return charmap.KOI8R.NewDecoder()
+	}
+	return nil
+}
+
+// detectUTF16 recognize UTF-16 without BOM: high bytes of Latin and Cyrillic characters are 0x00 and 0x04
+func detectUTF16(sample []byte) Encoding {
+	pairs := len(sample) / 2
+	if pairs < 2 {
+		return ""
+	}
+	var high [2]int
+	for i := 0; i < pairs*2; i++ {
+		if sample[i] == 0x00 || sample[i] == 0x04 {
+			high[i%2]++
+		}
+	}
+	even, odd := float64(high[0])/float64(pairs), float64(high[1])/float64(pairs)
+	switch {
+	case odd > 0.7 && even < 0.3:
+		return UTF16LE
+	case even > 0.7 && odd < 0.3:
+		return UTF16BE
+	}
+	return ""
+}
+
+// validPrefix report whether sample is valid UTF-8, except for a rune cut at its end
+func validPrefix(sample []byte) bool {
+	for i := len(sample) - 1; i >= 0 && i >= len(sample)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(sample[i]) {
+			if !utf8.FullRune(sample[i:]) {
+				sample = sample[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(sample)
+}
+
+// detectCyrillic choose Windows-1251 or KOI8-R, whichever decodes sample to more frequent lower-case letters,
+// empty if sample decoded by neither of them looks like russian text
+//
+// the same bytes are lower-case in one of them and upper-case in another, and most of text is lower-case
+func detectCyrillic(sample []byte) Encoding {
+	var best Encoding
+	bestScore := 0
+	for _, candidate := range []struct {
+		encoding Encoding
+		charmap  *charmap.Charmap
+	}{
+		{Windows1251, charmap.Windows1251},
+		{KOI8R, charmap.KOI8R},
+	} {
+		if score, ok := russianScore(sample, candidate.charmap); ok && score > bestScore {
+			best, bestScore = candidate.encoding, score
+		}
+	}
+	return best
+}
+
+const (
+	// minFrequentBigrams is a minimal share of frequent bigrams among bigrams of russian letters, in percents
+	minFrequentBigrams = 25
+	// maxMixedBigrams is a maximal share of russian letters adjacent to other letters, in percents
+	maxMixedBigrams = 5
+	// bigramsChecked is a number of bigrams from which their profile is checked
+	bigramsChecked = 20
+)
+
+// frequentBigrams are the most frequent letter pairs of russian texts
+const frequentBigrams = "ст но то на ен ов ни ра во ко ро пр ал ре ле по ер не та ос ол ли ом ет ть " +
+	"ан ва ка ор ат ел од ит ло ес за ри ви де ди ин ту те ми ак ля ся ых ой ый ие го ск ны ма"
+
+// russianScore return score of sample decoded by charmap, ok is false if decoded text is not russian
+//
+// text is russian if most of its high bytes and letters are russian letters,
+// they are rarely adjacent to other letters and their pairs are frequent in russian
+func russianScore(sample []byte, charmap *charmap.Charmap) (int, bool) {
+	high, letters, russian, score := 0, 0, 0, 0
+	bigrams, frequent, mixed := 0, 0, 0
+	var prev rune
+	for _, b := range sample {
+		r := charmap.DecodeByte(b)
+		if b >= 0x80 {
+			high++
+		}
+		switch {
+		case isRussianLetter(r):
+			letters++
+			russian++
+			if unicode.IsLower(r) {
+				score++
+				if strings.ContainsRune(frequentLetters, r) {
+					score += 2
+				}
+			}
+			if isRussianLetter(prev) {
+				bigrams++
+				var pair [2 * utf8.UTFMax]byte
+				bigram := utf8.AppendRune(utf8.AppendRune(pair[:0], unicode.ToLower(prev)), unicode.ToLower(r))
+				if strings.Contains(frequentBigrams, string(bigram)) {
+					frequent++
+				}
+			} else if unicode.IsLetter(prev) {
+				mixed++
+			}
+		case unicode.IsLetter(r):
+			letters++
+			if isRussianLetter(prev) {
+				mixed++
+			}
+		}
+		prev = r
+	}
+	switch {
+	case high == 0 || russian*2 < high || russian*2 < letters:
+		return 0, false
+	case mixed*100 > max(bigrams, 1)*maxMixedBigrams:
+		return 0, false
+	case bigrams >= bigramsChecked && frequent*100 < bigrams*minFrequentBigrams:
+		return 0, false
+	}
+	return score, true
+}
+
+func isRussianLetter(r rune) bool {
+	return r >= 'А' && r <= 'я' || r == 'ё' || r == 'Ё'
+}
+
+// validReader fail with ErrInvalidUTF8 on invalid UTF-8, a rune split between reads is checked once complete
+type validReader struct {
+	r    io.Reader
+	tail []byte
+}
+
+func (v *validReader) Read(p []byte) (int, error) {
+	n, err := v.r.Read(p)
+	data := append(v.tail, p[:n]...)
+	cut := len(data)
+	if err == nil {
+		for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(data[i]) {
+				if !utf8.FullRune(data[i:]) {
+					cut = i
+				}
+				break
+			}
+		}
+	}
+	if !utf8.Valid(data[:cut]) {
+		return 0, ErrInvalidUTF8
+	}
+	v.tail = append(v.tail[:0], data[cut:]...)
+	return n, err
+}
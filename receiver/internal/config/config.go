@@ -2,10 +2,23 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"receiver/cache"
+	"receiver/internal/auth"
+	"receiver/internal/blob"
+	"receiver/internal/events"
+	"receiver/internal/search"
+	"receiver/internal/similarity"
 	"receiver/internal/storage"
+	"receiver/internal/webhook"
+	"strconv"
+	"time"
 
+	"github.com/Critma/textAnalyzer/shared/ratelimit"
+	"github.com/Critma/textAnalyzer/shared/signature"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -13,19 +26,176 @@ type Application struct {
 	Config     *Config
 	Store      storage.Store
 	Redis      *redis.Client
+	Cache      *cache.RequestCache
 	HttpClient *http.Client
+	Webhooks   *webhook.Dispatcher
+	Events     *events.Hub
+	Keys       *auth.Keys
+	// Verifier check signed callbacks of analyzer
+	Verifier *signature.Verifier
+	Limiter  *ratelimit.Limiter
+	Blobs    *blob.Store
+	// Similar index signatures of analyzed requests
+	Similar *similarity.Index
+	// Search index texts of analyzed requests
+	Search *search.Index
 }
 
 type Config struct {
 	Addr         string
 	AnalyzerAddr string
 	RedisAddr    string
+
+	Retention Retention
+	Webhook   Webhook
+	Sync      Sync
+	Similar   Similar
+
+	IdempotencyTTL time.Duration
+	Cache          Cache
+	Auth           Auth
+	Internal       Internal
+	RateLimit      RateLimit
+	Limits         Limits
+	// BlobDir keep large documents, shared with analyzer
+	BlobDir string
+}
+
+// Limits of request size
+type Limits struct {
+	// MaxBodyBytes of json requests
+	MaxBodyBytes int64
+	// MaxTextChars of inline text, larger texts are uploaded as documents
+	MaxTextChars int
+	// MaxDocumentBytes of uploaded document
+	MaxDocumentBytes int64
+}
+
+// RateLimit of public api, zero rate disable a bucket
+type RateLimit struct {
+	// Client bucket is per api key, or per ip if auth is disabled
+	Client ratelimit.Limit
+	// IP bucket is per ip before api key is checked, if auth is enabled
+	IP ratelimit.Limit
+	// Global bucket is shared by all clients
+	Global ratelimit.Limit
+}
+
+// Internal is a listener of service-to-service endpoints
+type Internal struct {
+	// Addr of internal listener, empty serve internal endpoints on the public one
+	Addr string
+	// Secret sign analyzer callbacks, required
+	Secret string `json:"-"`
+	// MaxSkew of callback timestamp
+	MaxSkew time.Duration
+	// TLS enable https on internal listener, ClientCA also require client certificates (mTLS)
+	TLSCert     string
+	TLSKey      string
+	TLSClientCA string
+}
+
+// Auth of public api
+type Auth struct {
+	// Enabled require api key on public endpoints and scope requests to key tenant
+	Enabled bool
+	// AdminToken guard api keys management, empty disable it
+	AdminToken string `json:"-"`
+}
+
+// Cache size and ttl of two-tier request cache
+type Cache struct {
+	LocalSize   int
+	LocalTTL    time.Duration
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+
+// Sync limit synchronous analysis
+type Sync struct {
+	Timeout  time.Duration
+	MaxChars int
+}
+
+// Similar configure near-duplicate search over analyzed requests
+type Similar struct {
+	// Bands of MinHash signature in LSH index, more bands find less similar texts
+	Bands int
+	// MinScore is a minimal estimated Jaccard similarity of reported requests
+	MinScore float64
+	// Limit of similar requests in result
+	Limit int
+}
+
+// Retention is a policy of purging old texts
+type Retention struct {
+	// Days after which text is purged, 0 disable purging
+	Days int
+	// KeepMetrics anonymize request (drop text only) instead of deleting it
+	KeepMetrics bool
+	Interval    time.Duration
+}
+
+// Webhook is a policy of callback deliveries
+type Webhook struct {
+	// Secret sign callback payloads, empty disable signing
+	Secret      string `json:"-"`
+	MaxAttempts int
+	// Backoff before the first retry, doubled on each next one
+	Backoff time.Duration
 }
 
+// maxWebhookAttempts bound WEBHOOK_MAX_ATTEMPTS, every attempt is recorded in delivery log
+const maxWebhookAttempts = 100
+
 const (
 	RECEIVER_ADDR = "RECEIVER_ADDR"
 	ANALYZER_ADDR = "ANALYZER_ADDR"
 	REDIS_ADDR    = "REDIS_ADDR"
+
+	RETENTION_DAYS         = "RETENTION_DAYS"
+	RETENTION_KEEP_METRICS = "RETENTION_KEEP_METRICS"
+	RETENTION_INTERVAL     = "RETENTION_INTERVAL"
+
+	WEBHOOK_SECRET       = "WEBHOOK_SECRET"
+	WEBHOOK_MAX_ATTEMPTS = "WEBHOOK_MAX_ATTEMPTS"
+	WEBHOOK_BACKOFF      = "WEBHOOK_BACKOFF"
+
+	SYNC_TIMEOUT   = "SYNC_TIMEOUT"
+	SYNC_MAX_CHARS = "SYNC_MAX_CHARS"
+
+	SIMILAR_BANDS     = "SIMILAR_BANDS"
+	SIMILAR_MIN_SCORE = "SIMILAR_MIN_SCORE"
+	SIMILAR_LIMIT     = "SIMILAR_LIMIT"
+
+	IDEMPOTENCY_TTL = "IDEMPOTENCY_TTL"
+
+	CACHE_LOCAL_SIZE   = "CACHE_LOCAL_SIZE"
+	CACHE_LOCAL_TTL    = "CACHE_LOCAL_TTL"
+	CACHE_TTL          = "CACHE_TTL"
+	CACHE_NEGATIVE_TTL = "CACHE_NEGATIVE_TTL"
+
+	AUTH_ENABLED = "AUTH_ENABLED"
+	ADMIN_TOKEN  = "ADMIN_TOKEN"
+
+	INTERNAL_ADDR          = "INTERNAL_ADDR"
+	INTERNAL_SECRET        = "INTERNAL_SECRET"
+	INTERNAL_MAX_SKEW      = "INTERNAL_MAX_SKEW"
+	INTERNAL_TLS_CERT      = "INTERNAL_TLS_CERT"
+	INTERNAL_TLS_KEY       = "INTERNAL_TLS_KEY"
+	INTERNAL_TLS_CLIENT_CA = "INTERNAL_TLS_CLIENT_CA"
+
+	RATE_LIMIT_RPS          = "RATE_LIMIT_RPS"
+	RATE_LIMIT_BURST        = "RATE_LIMIT_BURST"
+	RATE_LIMIT_IP_RPS       = "RATE_LIMIT_IP_RPS"
+	RATE_LIMIT_IP_BURST     = "RATE_LIMIT_IP_BURST"
+	RATE_LIMIT_GLOBAL_RPS   = "RATE_LIMIT_GLOBAL_RPS"
+	RATE_LIMIT_GLOBAL_BURST = "RATE_LIMIT_GLOBAL_BURST"
+
+	MAX_BODY_BYTES     = "MAX_BODY_BYTES"
+	MAX_TEXT_CHARS     = "MAX_TEXT_CHARS"
+	MAX_DOCUMENT_BYTES = "MAX_DOCUMENT_BYTES"
+	BLOB_DIR           = "BLOB_DIR"
 )
 
 func Load() (*Config, error) {
@@ -48,5 +218,203 @@ func Load() (*Config, error) {
 		return nil, errors.New("failed to get " + REDIS_ADDR)
 	}
 	cfg.RedisAddr = addr
+
+	var err error
+	if cfg.Retention.Days, err = getEnvInt(RETENTION_DAYS, 0); err != nil {
+		return nil, err
+	}
+	if cfg.Retention.KeepMetrics, err = getEnvBool(RETENTION_KEEP_METRICS, true); err != nil {
+		return nil, err
+	}
+	if cfg.Retention.Interval, err = getEnvDuration(RETENTION_INTERVAL, time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.Retention.Interval <= 0 {
+		return nil, errors.New(RETENTION_INTERVAL + " must be positive")
+	}
+
+	cfg.Webhook.Secret = os.Getenv(WEBHOOK_SECRET)
+	if cfg.Webhook.MaxAttempts, err = getEnvInt(WEBHOOK_MAX_ATTEMPTS, 5); err != nil {
+		return nil, err
+	}
+	if cfg.Webhook.MaxAttempts < 1 || cfg.Webhook.MaxAttempts > maxWebhookAttempts {
+		return nil, fmt.Errorf("%s must be between 1 and %d", WEBHOOK_MAX_ATTEMPTS, maxWebhookAttempts)
+	}
+	if cfg.Webhook.Backoff, err = getEnvDuration(WEBHOOK_BACKOFF, time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Webhook.Backoff < 0 {
+		return nil, errors.New(WEBHOOK_BACKOFF + " must not be negative")
+	}
+
+	if cfg.Sync.Timeout, err = getEnvDuration(SYNC_TIMEOUT, 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Sync.Timeout <= 0 {
+		return nil, errors.New(SYNC_TIMEOUT + " must be positive")
+	}
+	if cfg.Sync.MaxChars, err = getEnvInt(SYNC_MAX_CHARS, 10000); err != nil {
+		return nil, err
+	}
+	if cfg.Sync.MaxChars <= 0 {
+		return nil, errors.New(SYNC_MAX_CHARS + " must be positive")
+	}
+
+	if cfg.Similar.Bands, err = getEnvInt(SIMILAR_BANDS, 32); err != nil {
+		return nil, err
+	}
+	if cfg.Similar.Bands <= 0 || similarity.SignatureSize%cfg.Similar.Bands != 0 {
+		return nil, fmt.Errorf("%s must divide MinHash signature size %d", SIMILAR_BANDS, similarity.SignatureSize)
+	}
+	if cfg.Similar.MinScore, err = getEnvFloat(SIMILAR_MIN_SCORE, 0.5); err != nil {
+		return nil, err
+	}
+	if cfg.Similar.MinScore < 0 || cfg.Similar.MinScore > 1 {
+		return nil, errors.New(SIMILAR_MIN_SCORE + " must be between 0 and 1")
+	}
+	if cfg.Similar.Limit, err = getEnvInt(SIMILAR_LIMIT, 5); err != nil {
+		return nil, err
+	}
+
+	if cfg.IdempotencyTTL, err = getEnvDuration(IDEMPOTENCY_TTL, 24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		return nil, errors.New(IDEMPOTENCY_TTL + " must be positive")
+	}
+
+	if cfg.Cache.LocalSize, err = getEnvInt(CACHE_LOCAL_SIZE, 1000); err != nil {
+		return nil, err
+	}
+	if cfg.Cache.LocalTTL, err = getEnvDuration(CACHE_LOCAL_TTL, 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Cache.TTL, err = getEnvDuration(CACHE_TTL, 5*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.Cache.NegativeTTL, err = getEnvDuration(CACHE_NEGATIVE_TTL, 10*time.Second); err != nil {
+		return nil, err
+	}
+
+	if cfg.Auth.Enabled, err = getEnvBool(AUTH_ENABLED, false); err != nil {
+		return nil, err
+	}
+	cfg.Auth.AdminToken = os.Getenv(ADMIN_TOKEN)
+
+	cfg.Internal.Addr = os.Getenv(INTERNAL_ADDR)
+	cfg.Internal.Secret = os.Getenv(INTERNAL_SECRET)
+	if cfg.Internal.Secret == "" {
+		return nil, errors.New(INTERNAL_SECRET + " must be set")
+	}
+	if cfg.Internal.MaxSkew, err = getEnvDuration(INTERNAL_MAX_SKEW, 5*time.Minute); err != nil {
+		return nil, err
+	}
+	cfg.Internal.TLSCert = os.Getenv(INTERNAL_TLS_CERT)
+	cfg.Internal.TLSKey = os.Getenv(INTERNAL_TLS_KEY)
+	cfg.Internal.TLSClientCA = os.Getenv(INTERNAL_TLS_CLIENT_CA)
+	if (cfg.Internal.TLSCert == "") != (cfg.Internal.TLSKey == "") {
+		return nil, errors.New(INTERNAL_TLS_CERT + " and " + INTERNAL_TLS_KEY + " must be set together")
+	}
+	if cfg.Internal.TLSClientCA != "" && cfg.Internal.TLSCert == "" {
+		return nil, errors.New(INTERNAL_TLS_CLIENT_CA + " requires " + INTERNAL_TLS_CERT)
+	}
+
+	if cfg.RateLimit.Client.Rate, err = getEnvFloat(RATE_LIMIT_RPS, 10); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.Client.Burst, err = getEnvInt(RATE_LIMIT_BURST, 20); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.IP.Rate, err = getEnvFloat(RATE_LIMIT_IP_RPS, cfg.RateLimit.Client.Rate); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.IP.Burst, err = getEnvInt(RATE_LIMIT_IP_BURST, cfg.RateLimit.Client.Burst); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.Global.Rate, err = getEnvFloat(RATE_LIMIT_GLOBAL_RPS, 500); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit.Global.Burst, err = getEnvInt(RATE_LIMIT_GLOBAL_BURST, 1000); err != nil {
+		return nil, err
+	}
+
+	if cfg.Limits.MaxBodyBytes, err = getEnvInt64(MAX_BODY_BYTES, 1<<20); err != nil {
+		return nil, err
+	}
+	if cfg.Limits.MaxTextChars, err = getEnvInt(MAX_TEXT_CHARS, 100000); err != nil {
+		return nil, err
+	}
+	if cfg.Limits.MaxDocumentBytes, err = getEnvInt64(MAX_DOCUMENT_BYTES, 64<<20); err != nil {
+		return nil, err
+	}
+	cfg.BlobDir = os.Getenv(BLOB_DIR)
+	if cfg.BlobDir == "" {
+		cfg.BlobDir = filepath.Join(os.TempDir(), "textanalyzer-blobs")
+	}
 	return cfg, nil
 }
+
+// getEnvInt return int env value or def if not set
+func getEnvInt(key string, def int) (int, error) {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// getEnvInt64 return int64 env value or def if not set
+func getEnvInt64(key string, def int64) (int64, error) {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// getEnvFloat return float env value or def if not set
+func getEnvFloat(key string, def float64) (float64, error) {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def, nil
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return f, nil
+}
+
+// getEnvBool return bool env value or def if not set
+func getEnvBool(key string, def bool) (bool, error) {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def, nil
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// getEnvDuration return duration env value (e.g. 30s, 1h) or def if not set
+func getEnvDuration(key string, def time.Duration) (time.Duration, error) {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return def, nil
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+	return d, nil
+}
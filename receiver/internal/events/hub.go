@@ -0,0 +1,189 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"receiver/internal/storage"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// statusChannel is a redis pub/sub channel shared by all receiver replicas
+const statusChannel = "receiver:status"
+
+// subscriptionBuffer is a number of events kept for a slow subscriber
+const subscriptionBuffer = 16
+
+// StatusEvent is published on every request status change
+type StatusEvent struct {
+	ID      uuid.UUID      `json:"id"`
+	Status  storage.Status `json:"status"`
+	Analyze *EventAnalyze  `json:"analyze,omitempty"`
+	// Progress of document analysis, set while request is in process
+	Progress  int       `json:"progress,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Revision of request, replicas drop cached copies with lower revision
+	Revision uint64 `json:"revision"`
+}
+
+type EventAnalyze struct {
+	WordCount         int     `json:"wordCount,omitempty"`
+	CharCount         int     `json:"charCount,omitempty"`
+	SentenceCount     int     `json:"sentenceCount,omitempty"`
+	AverageWordLength float64 `json:"averageWordLength,omitempty"`
+	UniqueWordCount   int     `json:"uniqueWordCount,omitempty"`
+}
+
+// NewStatusEvent return event of request current state, analyze is set on success only
+func NewStatusEvent(request *storage.TextRequest) StatusEvent {
+	event := StatusEvent{ID: request.ID, Status: request.Status, UpdatedAt: request.UpdatedAt, Revision: request.Revision}
+	if request.Status == storage.InProcess {
+		event.Progress = request.Progress
+	}
+	if request.Status == storage.Success {
+		event.Analyze = &EventAnalyze{
+			WordCount:         request.Analyze.WordCount,
+			CharCount:         request.Analyze.CharCount,
+			SentenceCount:     request.Analyze.SentenceCount,
+			AverageWordLength: request.Analyze.AverageWordLength,
+			UniqueWordCount:   request.Analyze.UniqueWordCount,
+		}
+	}
+	return event
+}
+
+// Hub fan out status events to local subscribers, events of other replicas come through redis
+type Hub struct {
+	rdb *redis.Client
+
+	mu   sync.RWMutex
+	subs map[uuid.UUID]map[*Subscription]struct{}
+	// listeners receive every event, guarded by mu
+	listeners []func(StatusEvent)
+}
+
+func NewHub(rdb *redis.Client) *Hub {
+	return &Hub{
+		rdb:  rdb,
+		subs: make(map[uuid.UUID]map[*Subscription]struct{}),
+	}
+}
+
+// Run receive events from redis until ctx is done
+func (h *Hub) Run(ctx context.Context) {
+	pubsub := h.rdb.Subscribe(ctx, statusChannel)
+	defer pubsub.Close()
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-messages:
+			if !ok {
+				return
+			}
+			var event StatusEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				log.Error().Str("event", "status event").Err(err).Msg("Failed to unmarshal")
+				continue
+			}
+			h.dispatch(event)
+		}
+	}
+}
+
+// Publish send event to subscribers of all replicas
+//
+// if redis is unavailable, event is delivered to local subscribers only
+func (h *Hub) Publish(event StatusEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Error().Str("event", "status event").Any("obj", event).Err(err).Msg("Failed to marshal object")
+		return
+	}
+	if err := h.rdb.Publish(context.Background(), statusChannel, data).Err(); err != nil {
+		log.Error().Str("event", "status event").Str("requestID", event.ID.String()).Err(err).Msg("redis publish error")
+		h.dispatch(event)
+	}
+}
+
+// Subscribe return subscription on events of ids, more ids may be added later
+func (h *Hub) Subscribe(ids ...uuid.UUID) *Subscription {
+	sub := &Subscription{hub: h, C: make(chan StatusEvent, subscriptionBuffer), ids: make(map[uuid.UUID]struct{})}
+	sub.Add(ids...)
+	return sub
+}
+
+// Listen call fn on every event of all replicas, fn must not block
+func (h *Hub) Listen(fn func(StatusEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.listeners = append(h.listeners, fn)
+}
+
+func (h *Hub) dispatch(event StatusEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, fn := range h.listeners {
+		fn(event)
+	}
+	for sub := range h.subs[event.ID] {
+		select {
+		case sub.C <- event:
+		default:
+			log.Error().Str("event", "status event").Str("requestID", event.ID.String()).Msg("subscriber is too slow, event dropped")
+		}
+	}
+}
+
+// Subscription receive events of its ids on C until Close
+type Subscription struct {
+	C   chan StatusEvent
+	hub *Hub
+	// ids guarded by hub.mu
+	ids map[uuid.UUID]struct{}
+}
+
+// Add subscribe on events of ids
+func (s *Subscription) Add(ids ...uuid.UUID) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	for _, id := range ids {
+		if s.hub.subs[id] == nil {
+			s.hub.subs[id] = make(map[*Subscription]struct{})
+		}
+		s.hub.subs[id][s] = struct{}{}
+		s.ids[id] = struct{}{}
+	}
+}
+
+// Remove unsubscribe from events of ids
+func (s *Subscription) Remove(ids ...uuid.UUID) {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	for _, id := range ids {
+		s.remove(id)
+	}
+}
+
+// Close unsubscribe from all events
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	for id := range s.ids {
+		s.remove(id)
+	}
+}
+
+// remove must be called under hub.mu
+func (s *Subscription) remove(id uuid.UUID) {
+	delete(s.ids, id)
+	delete(s.hub.subs[id], s)
+	if len(s.hub.subs[id]) == 0 {
+		delete(s.hub.subs, id)
+	}
+}
@@ -0,0 +1,97 @@
+package extract
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"net/url"
+	"path"
+)
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Manifest []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// extractEPUB write text of book documents in reading order (spine of package document)
+func extractEPUB(archive *zip.Reader, out *limitedBuilder) error {
+	var container epubContainer
+	if err := decodeEntry(archive, "META-INF/container.xml", &container); err != nil {
+		return err
+	}
+	if len(container.Rootfiles) == 0 {
+		return errors.New("epub has no package document")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+	var pkg epubPackage
+	if err := decodeEntry(archive, opfPath, &pkg); err != nil {
+		return err
+	}
+
+	hrefs := make(map[string]string, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		if item.MediaType == "application/xhtml+xml" || item.MediaType == "text/html" {
+			hrefs[item.ID] = item.Href
+		}
+	}
+	// unpacked size of all documents is bounded, documents repeated in spine are read once
+	left := out.limit * markupRatio
+	read := make(map[string]bool)
+	for _, itemref := range pkg.Spine {
+		href, ok := hrefs[itemref.IDRef]
+		if !ok {
+			continue
+		}
+		// hrefs are relative to package document
+		if unescaped, err := url.PathUnescape(href); err == nil {
+			href = unescaped
+		}
+		name := path.Join(path.Dir(opfPath), href)
+		if read[name] {
+			continue
+		}
+		read[name] = true
+		file, err := openEntry(archive, name)
+		if err != nil {
+			return err
+		}
+		rc, err := openLimited(file, &left)
+		if err != nil {
+			return err
+		}
+		err = extractHTML(rc, out)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if _, err := out.WriteString("\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeEntry unmarshal small xml entry of archive
+func decodeEntry(archive *zip.Reader, name string, v any) error {
+	file, err := openEntry(archive, name)
+	if err != nil {
+		return err
+	}
+	content, err := readEntry(file, 1<<20)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(content, v)
+}
@@ -0,0 +1,247 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"receiver/internal/charset"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format of uploaded file
+type Format string
+
+var (
+	Text     Format = "txt"
+	Markdown Format = "md"
+	HTML     Format = "html"
+	DOCX     Format = "docx"
+	ODT      Format = "odt"
+	EPUB     Format = "epub"
+)
+
+var (
+	ErrUnsupported = errors.New("unsupported file format")
+	ErrTooLarge    = errors.New("extracted text is too large")
+)
+
+// Document is a text extracted from file
+type Document struct {
+	Text   string
+	Format Format
+	// Encoding of source text, archive formats are always UTF-8
+	Encoding charset.Encoding
+}
+
+// sniffLen is a number of bytes used to detect format by content
+const sniffLen = 512
+
+var zipMagic = []byte("PK\x03\x04")
+
+// Extract detect format of file by content and name, and return its plain text
+//
+// text files are transcoded to UTF-8 from detected encoding, text that is not valid UTF-8 is charset.ErrInvalidUTF8,
+// text longer than limit bytes is ErrTooLarge, archives are never unpacked beyond limit
+func Extract(name string, r io.ReaderAt, size, limit int64) (*Document, error) {
+	head := make([]byte, min(size, sniffLen))
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(head, zipMagic) {
+		archive, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, err
+		}
+		format, err := detectArchive(archive)
+		if err != nil {
+			return nil, err
+		}
+		out := newLimitedBuilder(limit)
+		switch format {
+		case DOCX:
+			err = extractDOCX(archive, out)
+		case ODT:
+			err = extractODT(archive, out)
+		case EPUB:
+			err = extractEPUB(archive, out)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !utf8.ValidString(out.String()) {
+			return nil, charset.ErrInvalidUTF8
+		}
+		return &Document{Text: out.String(), Format: format, Encoding: charset.UTF8}, nil
+	}
+
+	format, err := detectText(name, head)
+	if err != nil {
+		return nil, err
+	}
+	if size > limit {
+		return nil, ErrTooLarge
+	}
+	content, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	text, encoding, err := charset.Decode(content)
+	if err != nil {
+		return nil, err
+	}
+	out := newLimitedBuilder(limit)
+	switch format {
+	case Text:
+		_, err = out.WriteString(text)
+	case Markdown:
+		err = extractMarkdown(text, out)
+	case HTML:
+		err = extractHTML(strings.NewReader(text), out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Text: out.String(), Format: format, Encoding: encoding}, nil
+}
+
+// detectArchive return format of zip by its mimetype entry or well-known parts
+func detectArchive(archive *zip.Reader) (Format, error) {
+	for _, file := range archive.File {
+		switch file.Name {
+		case "mimetype":
+			mimetype, err := readEntry(file, sniffLen)
+			if err != nil {
+				return "", err
+			}
+			switch strings.TrimSpace(string(mimetype)) {
+			case "application/epub+zip":
+				return EPUB, nil
+			case "application/vnd.oasis.opendocument.text":
+				return ODT, nil
+			}
+		case "word/document.xml":
+			return DOCX, nil
+		}
+	}
+	return "", ErrUnsupported
+}
+
+// detectText return format of text file by extension, or by content if there is no extension
+func detectText(name string, head []byte) (Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".md", ".markdown":
+		return Markdown, nil
+	case ".html", ".htm", ".xhtml":
+		return HTML, nil
+	case ".txt", ".text":
+		return Text, nil
+	case "":
+	default:
+		return "", ErrUnsupported
+	}
+	contentType := http.DetectContentType(head)
+	switch {
+	case strings.HasPrefix(contentType, "text/html"):
+		return HTML, nil
+	case strings.HasPrefix(contentType, "text/"):
+		return Text, nil
+	}
+	// UTF-16 without BOM is sniffed as binary
+	if encoding := charset.Detect(head); encoding == charset.UTF16LE || encoding == charset.UTF16BE {
+		return Text, nil
+	}
+	return "", ErrUnsupported
+}
+
+// readEntry return content of zip entry up to limit bytes, ErrTooLarge if it is longer
+func readEntry(file *zip.File, limit int64) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(content)) > limit {
+		return nil, ErrTooLarge
+	}
+	return content, nil
+}
+
+// markupRatio bound unpacked size of archive xml entries, as a multiple of text limit
+const markupRatio = 8
+
+// openLimited open zip entry, reading it is charged to left bytes shared by all entries of archive,
+// reading more than left is ErrTooLarge
+func openLimited(file *zip.File, left *int64) (io.ReadCloser, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	return &limitedReader{ReadCloser: rc, left: left}, nil
+}
+
+type limitedReader struct {
+	io.ReadCloser
+	left *int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if *r.left <= 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > *r.left {
+		p = p[:*r.left]
+	}
+	n, err := r.ReadCloser.Read(p)
+	*r.left -= int64(n)
+	return n, err
+}
+
+// openEntry return zip entry by name
+func openEntry(archive *zip.Reader, name string) (*zip.File, error) {
+	for _, file := range archive.File {
+		if file.Name == name {
+			return file, nil
+		}
+	}
+	return nil, errors.New("missing " + name)
+}
+
+// limitedBuilder collect text up to limit bytes
+type limitedBuilder struct {
+	strings.Builder
+	limit int64
+}
+
+func newLimitedBuilder(limit int64) *limitedBuilder {
+	return &limitedBuilder{limit: limit}
+}
+
+func (b *limitedBuilder) WriteString(s string) (int, error) {
+	if int64(b.Len()+len(s)) > b.limit {
+		return 0, ErrTooLarge
+	}
+	return b.Builder.WriteString(s)
+}
+
+// atLineStart report whether text is empty or ends with newline
+func (b *limitedBuilder) atLineStart() bool {
+	return b.Len() == 0 || strings.HasSuffix(b.String(), "\n")
+}
+
+// newline end current line, unless it is empty
+func (b *limitedBuilder) newline() error {
+	if b.atLineStart() {
+		return nil
+	}
+	_, err := b.WriteString("\n")
+	return err
+}
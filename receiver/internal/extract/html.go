@@ -0,0 +1,101 @@
+package extract
+
+import (
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skippedElements have no readable text
+var skippedElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true, atom.Svg: true,
+}
+
+// blockElements start a new line
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true, atom.Br: true,
+	atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Footer: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true, atom.Nav: true, atom.Ol: true,
+	atom.P: true, atom.Pre: true, atom.Section: true, atom.Table: true, atom.Td: true, atom.Th: true,
+	atom.Tr: true, atom.Ul: true,
+}
+
+// extractHTML write text of html document, markup is dropped, whitespace is collapsed and blocks are put on separate lines
+func extractHTML(r io.Reader, out *limitedBuilder) error {
+	tokenizer := html.NewTokenizer(r)
+	text := &collapsedWriter{out: out}
+	skipped := 0
+	for {
+		token := tokenizer.Next()
+		switch token {
+		case html.ErrorToken:
+			if tokenizer.Err() == io.EOF {
+				return out.newline()
+			}
+			return tokenizer.Err()
+		case html.TextToken:
+			if skipped == 0 {
+				if err := text.write(string(tokenizer.Text())); err != nil {
+					return err
+				}
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := tokenizer.TagName()
+			tag := atom.Lookup(name)
+			if skippedElements[tag] && token != html.SelfClosingTagToken {
+				if token == html.StartTagToken {
+					skipped++
+				} else if skipped > 0 {
+					skipped--
+				}
+			}
+			if blockElements[tag] {
+				if err := text.newline(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// collapsedWriter write text with runs of whitespace replaced by a single space
+type collapsedWriter struct {
+	out *limitedBuilder
+	// space is seen after the last written word
+	space bool
+}
+
+func (w *collapsedWriter) write(text string) error {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		w.space = w.space || text != ""
+		return nil
+	}
+	if first, _ := utf8.DecodeRuneInString(text); unicode.IsSpace(first) {
+		w.space = true
+	}
+	for i, word := range words {
+		if (w.space || i > 0) && !w.out.atLineStart() {
+			if _, err := w.out.WriteString(" "); err != nil {
+				return err
+			}
+		}
+		if _, err := w.out.WriteString(word); err != nil {
+			return err
+		}
+		w.space = false
+	}
+	last, _ := utf8.DecodeLastRuneInString(text)
+	w.space = unicode.IsSpace(last)
+	return nil
+}
+
+func (w *collapsedWriter) newline() error {
+	w.space = false
+	return w.out.newline()
+}
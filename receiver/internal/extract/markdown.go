@@ -0,0 +1,70 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdFence      = regexp.MustCompile("^\\s*(```|~~~)")
+	mdRule       = regexp.MustCompile(`^\s{0,3}((-\s*){3,}|(\*\s*){3,}|(_\s*){3,})$`)
+	mdSetext     = regexp.MustCompile(`^\s{0,3}(=+|-+)\s*$`)
+	mdTableRule  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	mdReference  = regexp.MustCompile(`^\s{0,3}\[[^\]]+\]:\s+\S+`)
+	mdBlockStart = regexp.MustCompile(`^\s{0,3}(#{1,6}\s+|>\s?|[-*+]\s+(\[[ xX]\]\s+)?|\d+[.)]\s+)`)
+	mdImage      = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink       = regexp.MustCompile(`\[([^\]]*)\](\([^)]*\)|\[[^\]]*\])`)
+	mdAutolink   = regexp.MustCompile(`<((https?|mailto):[^>\s]+)>`)
+	mdTag        = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	mdCode       = regexp.MustCompile("`+([^`]*)`+")
+	mdStrong     = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|__(\S(?:.*?\S)?)__|~~(\S(?:.*?\S)?)~~`)
+	mdEmphasis   = regexp.MustCompile(`(^|[^\w*])[*_](\S(?:.*?\S)?)[*_]([^\w*]|$)`)
+	mdClosingATX = regexp.MustCompile(`\s+#+\s*$`)
+)
+
+// extractMarkdown write text of markdown document without markup: headings, quotes and list markers,
+// emphasis, links and images (their text is kept), code fences and inline html
+func extractMarkdown(text string, out *limitedBuilder) error {
+	inFence := false
+	for line := range strings.Lines(text) {
+		line = strings.TrimRight(line, "\r\n")
+		if mdFence.MatchString(line) {
+			inFence = !inFence
+			continue
+		}
+		if !inFence {
+			line = markdownLine(line)
+		}
+		if _, err := out.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownLine return line of text without markdown markup
+func markdownLine(line string) string {
+	if mdRule.MatchString(line) || mdSetext.MatchString(line) || (mdTableRule.MatchString(line) && strings.Contains(line, "-")) || mdReference.MatchString(line) {
+		return ""
+	}
+	// nested quotes and lists
+	for {
+		stripped := mdBlockStart.ReplaceAllString(line, "")
+		if stripped == line {
+			break
+		}
+		line = stripped
+	}
+	line = mdClosingATX.ReplaceAllString(line, "")
+	line = mdImage.ReplaceAllString(line, "$1")
+	line = mdLink.ReplaceAllString(line, "$1")
+	line = mdAutolink.ReplaceAllString(line, "$1")
+	line = mdTag.ReplaceAllString(line, "")
+	line = mdCode.ReplaceAllString(line, "$1")
+	line = mdStrong.ReplaceAllString(line, "${1}${2}${3}")
+	line = mdEmphasis.ReplaceAllString(line, "${1}${2}${3}")
+	if strings.HasPrefix(strings.TrimSpace(line), "|") {
+		line = strings.Join(strings.Fields(strings.ReplaceAll(line, "|", " ")), " ")
+	}
+	return strings.TrimSpace(line)
+}
@@ -0,0 +1,123 @@
+package extract
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// extractDOCX write text of word/document.xml, paragraphs are put on separate lines
+func extractDOCX(archive *zip.Reader, out *limitedBuilder) error {
+	return extractXML(archive, "word/document.xml", out, func(decoder *xml.Decoder, token xml.Token, inText *int) error {
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				*inText++
+			case "tab":
+				_, err := out.WriteString("\t")
+				return err
+			case "br", "cr":
+				_, err := out.WriteString("\n")
+				return err
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				*inText--
+			case "p":
+				return out.newline()
+			}
+		}
+		return nil
+	})
+}
+
+// extractODT write text of content.xml, paragraphs and headings are put on separate lines
+func extractODT(archive *zip.Reader, out *limitedBuilder) error {
+	return extractXML(archive, "content.xml", out, func(decoder *xml.Decoder, token xml.Token, inText *int) error {
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p", "h":
+				*inText++
+			case "s":
+				// c spaces, one if not set, out of range count is too large
+				spaces := int64(1)
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "c" {
+						if n, err := strconv.ParseInt(attr.Value, 10, 64); n > 0 && (err == nil || errors.Is(err, strconv.ErrRange)) {
+							spaces = n
+						}
+					}
+				}
+				// checked before spaces are allocated
+				if spaces > out.limit-int64(out.Len()) {
+					return ErrTooLarge
+				}
+				_, err := out.WriteString(strings.Repeat(" ", int(spaces)))
+				return err
+			case "tab":
+				_, err := out.WriteString("\t")
+				return err
+			case "line-break":
+				_, err := out.WriteString("\n")
+				return err
+			case "annotation", "note":
+				// comments and footnotes are not a part of text
+				return decoder.Skip()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p", "h":
+				*inText--
+				return out.newline()
+			}
+		}
+		return nil
+	})
+}
+
+// extractXML stream tokens of archive entry to handle, character data is written while inText is positive
+func extractXML(archive *zip.Reader, name string, out *limitedBuilder, handle func(decoder *xml.Decoder, token xml.Token, inText *int) error) error {
+	file, err := openEntry(archive, name)
+	if err != nil {
+		return err
+	}
+	left := out.limit * markupRatio
+	rc, err := openLimited(file, &left)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return decodeXML(rc, out, handle)
+}
+
+func decodeXML(r io.Reader, out *limitedBuilder, handle func(decoder *xml.Decoder, token xml.Token, inText *int) error) error {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	inText := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return out.newline()
+		}
+		if err != nil {
+			return err
+		}
+		if data, ok := token.(xml.CharData); ok {
+			if inText > 0 {
+				if _, err := out.WriteString(string(data)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := handle(decoder, token, &inText); err != nil {
+			return err
+		}
+	}
+}
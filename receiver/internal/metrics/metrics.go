@@ -18,3 +18,43 @@ var requestMetrics = promauto.NewSummaryVec(prometheus.SummaryOpts{
 func ObserveRequest(d time.Duration, statusCode int, handlerName string) {
 	requestMetrics.WithLabelValues(strconv.Itoa(statusCode), handlerName).Observe(d.Seconds())
 }
+
+var dedupMetrics = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "receiver",
+	Subsystem: "dedup",
+	Name:      "lookups_total",
+	Help:      "Lookups of analyzed requests with the same text, hit ratio is hit / (hit + miss)",
+}, []string{"result"})
+
+// ObserveDedup count lookup of analyzed request with the same text
+func ObserveDedup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	dedupMetrics.WithLabelValues(result).Inc()
+}
+
+var rateLimitMetrics = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "receiver",
+	Subsystem: "ratelimit",
+	Name:      "requests_total",
+	Help:      "Requests checked by rate limiter per bucket scope, result is allowed, throttled or error",
+}, []string{"scope", "result"})
+
+// ObserveRateLimit count rate limiter decision of bucket scope (ip, client, global)
+func ObserveRateLimit(scope, result string) {
+	rateLimitMetrics.WithLabelValues(scope, result).Inc()
+}
+
+var cacheMetrics = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "receiver",
+	Subsystem: "cache",
+	Name:      "events_total",
+	Help:      "Cache hits, misses, evictions and errors per tier",
+}, []string{"cache", "tier", "event"})
+
+// ObserveCache count cache event (hit, miss, eviction, error) of tier
+func ObserveCache(cache, tier, event string) {
+	cacheMetrics.WithLabelValues(cache, tier, event).Inc()
+}
@@ -0,0 +1,127 @@
+package retention
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"receiver/internal/config"
+	"receiver/internal/storage"
+	"time"
+
+	"github.com/Critma/textAnalyzer/shared/signature"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Erase delete request and its comparisons from store, similarity and search indexes, blob store, receiver and analyzer caches,
+// and record it in audit
+//
+// cache errors are logged only, cached entries expire by ttl anyway
+func Erase(app config.Application, id uuid.UUID, reason string) error {
+	request, err := app.Store.Requests.DeleteRequest(id)
+	if err != nil {
+		return err
+	}
+	if _, err := app.Store.Comparisons.DeleteComparisons(storage.ComparisonFilter{RequestID: id}); err != nil {
+		return err
+	}
+	app.Similar.Remove(id)
+	app.Search.Remove(id)
+	evictCaches(app, request)
+	return audit(app, request, storage.Deleted, reason)
+}
+
+// Anonymize drop request text from store, caches and search index, passages of its comparisons
+// and its signature from similarity index, keep status, analyze result and comparison scores
+func Anonymize(app config.Application, id uuid.UUID, reason string) error {
+	request, err := app.Store.Requests.GetRequest(id)
+	if err != nil {
+		return err
+	}
+	text, blob, normalize := request.Text, request.Blob, request.Normalize
+	anonymized, err := app.Store.Requests.AnonymizeRequest(id)
+	if err != nil {
+		return err
+	}
+	if _, err := app.Store.Comparisons.RedactComparisons(storage.ComparisonFilter{RequestID: id}); err != nil {
+		return err
+	}
+	app.Similar.Remove(id)
+	app.Search.Remove(id)
+	// replace cached request instead of deleting it, so a stale copy with text is never cached again
+	app.Cache.Set(context.Background(), id.String(), *anonymized)
+	deleteBlob(app, id, blob)
+	if text != "" {
+		if err := evictFromAnalyzer(app, text, normalize); err != nil {
+			log.Error().Str("event", "erase request").Str("requestID", id.String()).Err(err).Msg("failed to evict from analyzer cache")
+		}
+	}
+	return audit(app, anonymized, storage.Anonymized, reason)
+}
+
+func evictCaches(app config.Application, request *storage.TextRequest) {
+	if err := app.Cache.Delete(context.Background(), request.ID.String()); err != nil {
+		log.Error().Str("event", "erase request").Str("requestID", request.ID.String()).Err(err).Msg("failed to delete from receiver cache")
+	}
+	deleteBlob(app, request.ID, request.Blob)
+	if request.Text == "" {
+		return
+	}
+	if err := evictFromAnalyzer(app, request.Text, request.Normalize); err != nil {
+		log.Error().Str("event", "erase request").Str("requestID", request.ID.String()).Err(err).Msg("failed to evict from analyzer cache")
+	}
+}
+
+// deleteBlob remove document of request from blob store, errors are logged only
+func deleteBlob(app config.Application, id uuid.UUID, blob string) {
+	if blob == "" {
+		return
+	}
+	if err := app.Blobs.Delete(blob); err != nil {
+		log.Error().Str("event", "erase request").Str("requestID", id.String()).Err(err).Msg("failed to delete blob")
+	}
+}
+
+func audit(app config.Application, request *storage.TextRequest, action storage.AuditAction, reason string) error {
+	record := storage.AuditRecord{
+		ID:        uuid.New(),
+		RequestID: request.ID,
+		Tenant:    request.Tenant,
+		Action:    action,
+		Reason:    reason,
+		At:        time.Now().UTC(),
+	}
+	log.Info().Str("event", "audit").Any("record", record).Msg("request erased")
+	return app.Store.Audit.AddRecord(record)
+}
+
+// evictFromAnalyzer ask analyzer service to drop cached analyze of text normalized by steps,
+// request is signed with INTERNAL_SECRET
+func evictFromAnalyzer(app config.Application, text string, normalize []string) error {
+	data, err := json.Marshal(map[string]any{"text": text, "normalize": normalize})
+	if err != nil {
+		return err
+	}
+
+	analyzerUrl := fmt.Sprintf("http://%s/api/v1/cache/evict", app.Config.AnalyzerAddr)
+	req, err := http.NewRequest(http.MethodPost, analyzerUrl, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := signature.SignRequest(req, app.Config.Internal.Secret, data); err != nil {
+		return err
+	}
+	resp, err := app.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("analyzer service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package retention
+
+import (
+	"context"
+	"receiver/internal/config"
+	"receiver/internal/storage"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// purgeBatchSize is a number of requests read from store at once
+const purgeBatchSize = 100
+
+const retentionReason = "retention policy"
+
+// Run purge expired texts every policy interval until ctx is done
+//
+// do nothing if policy is disabled
+func Run(ctx context.Context, app config.Application) {
+	policy := app.Config.Retention
+	if policy.Days <= 0 {
+		return
+	}
+	ticker := time.NewTicker(policy.Interval)
+	defer ticker.Stop()
+	for {
+		purged, err := Purge(app, time.Now().AddDate(0, 0, -policy.Days))
+		if err != nil {
+			log.Error().Str("event", "retention").Err(err).Msg("purge error")
+		} else if purged > 0 {
+			log.Info().Str("event", "retention").Int("purged", purged).Msg("expired texts purged")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge erase or anonymize (if policy keep metrics) texts and comparisons created before cutoff
+//
+// return number of purged requests and comparisons
+func Purge(app config.Application, cutoff time.Time) (int, error) {
+	purged, err := purgeRequests(app, cutoff)
+	if err != nil {
+		return purged, err
+	}
+	filter := storage.ComparisonFilter{CreatedBefore: cutoff}
+	var comparisons int
+	if app.Config.Retention.KeepMetrics {
+		comparisons, err = app.Store.Comparisons.RedactComparisons(filter)
+	} else {
+		comparisons, err = app.Store.Comparisons.DeleteComparisons(filter)
+	}
+	return purged + comparisons, err
+}
+
+func purgeRequests(app config.Application, cutoff time.Time) (int, error) {
+	query := storage.Query{To: cutoff, Limit: purgeBatchSize}
+	purged := 0
+	for {
+		batch, err := app.Store.Requests.ListRequests(query)
+		if err != nil {
+			return purged, err
+		}
+		for _, request := range batch {
+			query.After = storage.CursorOf(request)
+			// already anonymized, nothing to purge
+			if request.Text == "" && request.Blob == "" {
+				continue
+			}
+			if app.Config.Retention.KeepMetrics {
+				err = Anonymize(app, request.ID, retentionReason)
+			} else {
+				err = Erase(app, request.ID, retentionReason)
+			}
+			if err != nil && err != storage.ErrNotFound {
+				return purged, err
+			}
+			purged++
+		}
+		if len(batch) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
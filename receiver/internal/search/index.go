@@ -0,0 +1,400 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"math"
+	"net/http"
+	"receiver/internal/storage"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	queueSize  = 1000
+	workersNum = 2
+
+	// BM25 parameters
+	k1 = 1.2
+	b  = 0.75
+)
+
+// Index is an inverted index of texts of analyzed requests
+//
+// texts are tokenized by analyzer service, words are matched by stems,
+// prefixes by lower-cased words, documents are ranked by BM25 within tenant
+type Index struct {
+	tokenizer *tokenizer
+	jobs      chan storage.TextRequest
+
+	mu   sync.RWMutex
+	seq  uint64
+	docs map[uuid.UUID]*document
+	// pending are requests enqueued and not removed yet
+	pending map[uuid.UUID]struct{}
+	// stems and words map term to positions of it in every document
+	stems   map[string]postings
+	words   map[string]postings
+	tenants map[string]*corpus
+}
+
+type postings map[uuid.UUID][]int
+
+type document struct {
+	tenant string
+	text   string
+	tokens []Token
+	// seq is an order of indexing, newer documents rank first among equal scores
+	seq uint64
+}
+
+// corpus is a number of documents of tenant and their total length in tokens
+type corpus struct {
+	docs   int
+	length int
+}
+
+// Result is a page of documents matching query
+type Result struct {
+	Total int
+	Hits  []Hit
+}
+
+type Hit struct {
+	ID      uuid.UUID
+	Score   float64
+	Snippet string
+}
+
+// New return empty index, texts are tokenized by analyzer service at analyzerAddr,
+// requests to it are signed with secret
+func New(client *http.Client, analyzerAddr, secret string) *Index {
+	return &Index{
+		tokenizer: &tokenizer{client: client, addr: analyzerAddr, secret: secret},
+		jobs:      make(chan storage.TextRequest, queueSize),
+		docs:      make(map[uuid.UUID]*document),
+		pending:   make(map[uuid.UUID]struct{}),
+		stems:     make(map[string]postings),
+		words:     make(map[string]postings),
+		tenants:   make(map[string]*corpus),
+	}
+}
+
+// Run start indexing workers, return when ctx is done
+func (i *Index) Run(ctx context.Context) {
+	for range workersNum {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case request := <-i.jobs:
+					i.index(ctx, request)
+				}
+			}
+		}()
+	}
+	<-ctx.Done()
+}
+
+// Enqueue schedule indexing of request text, do nothing if request has no inline text
+//
+// documents in blob store are not indexed, index keeps whole texts in memory for snippets
+func (i *Index) Enqueue(request storage.TextRequest) {
+	if request.Text == "" {
+		return
+	}
+	i.mu.Lock()
+	i.pending[request.ID] = struct{}{}
+	i.mu.Unlock()
+	select {
+	case i.jobs <- request:
+	default:
+		log.Error().Str("event", "search index").Str("requestID", request.ID.String()).Msg("indexing queue is full")
+		i.mu.Lock()
+		delete(i.pending, request.ID)
+		i.mu.Unlock()
+	}
+}
+
+func (i *Index) index(ctx context.Context, request storage.TextRequest) {
+	tokens, err := i.tokenizer.tokenize(ctx, []string{request.Text})
+	if err != nil {
+		log.Error().Str("event", "search index").Str("requestID", request.ID.String()).Err(err).Msg("failed to tokenize text")
+		i.mu.Lock()
+		delete(i.pending, request.ID)
+		i.mu.Unlock()
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	// request is removed while it was tokenized
+	if _, ok := i.pending[request.ID]; !ok {
+		return
+	}
+	delete(i.pending, request.ID)
+	if _, ok := i.docs[request.ID]; ok {
+		return
+	}
+	i.seq++
+	doc := &document{tenant: request.Tenant, text: request.Text, tokens: tokens[0], seq: i.seq}
+	i.docs[request.ID] = doc
+	for pos, token := range doc.tokens {
+		add(i.stems, token.Stem, request.ID, pos)
+		add(i.words, token.Word, request.ID, pos)
+	}
+	c, ok := i.tenants[doc.tenant]
+	if !ok {
+		c = &corpus{}
+		i.tenants[doc.tenant] = c
+	}
+	c.docs++
+	c.length += len(doc.tokens)
+}
+
+// Remove drop request from index, request enqueued and not indexed yet is not indexed
+func (i *Index) Remove(id uuid.UUID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.pending, id)
+	doc, ok := i.docs[id]
+	if !ok {
+		return
+	}
+	delete(i.docs, id)
+	for _, token := range doc.tokens {
+		remove(i.stems, token.Stem, id)
+		remove(i.words, token.Word, id)
+	}
+	c := i.tenants[doc.tenant]
+	c.docs--
+	c.length -= len(doc.tokens)
+	if c.docs == 0 {
+		delete(i.tenants, doc.tenant)
+	}
+}
+
+func add(index map[string]postings, term string, id uuid.UUID, pos int) {
+	p, ok := index[term]
+	if !ok {
+		p = make(postings)
+		index[term] = p
+	}
+	p[id] = append(p[id], pos)
+}
+
+func remove(index map[string]postings, term string, id uuid.UUID) {
+	p, ok := index[term]
+	if !ok {
+		return
+	}
+	delete(p, id)
+	if len(p) == 0 {
+		delete(index, term)
+	}
+}
+
+// Search return page of documents of tenant matching query, the best ranked first
+//
+// query errors wrap ErrQuerySyntax or are ErrEmptyQuery
+func (i *Index) Search(ctx context.Context, query, tenant string, limit, offset int) (*Result, error) {
+	root, err := parseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	leaves := terms(root)
+	texts := make([]string, len(leaves))
+	for k, leaf := range leaves {
+		texts[k] = leaf.raw
+	}
+	tokens, err := i.tokenizer.tokenize(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	for k, leaf := range leaves {
+		if err := leaf.setTokens(tokens[k]); err != nil {
+			return nil, err
+		}
+	}
+
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	found := i.eval(root, tenant)
+	ids := make([]uuid.UUID, 0, len(found))
+	for id := range found {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(x, y uuid.UUID) int {
+		if c := cmp.Compare(found[y].score, found[x].score); c != 0 {
+			return c
+		}
+		return cmp.Compare(i.docs[y].seq, i.docs[x].seq)
+	})
+
+	result := &Result{Total: len(ids), Hits: make([]Hit, 0)}
+	// offset is clamped before limit is added, so large offsets do not overflow
+	from := min(offset, len(ids))
+	to := from + min(limit, len(ids)-from)
+	for _, id := range ids[from:to] {
+		result.Hits = append(result.Hits, Hit{
+			ID:      id,
+			Score:   math.Round(found[id].score*1000) / 1000,
+			Snippet: snippet(i.docs[id], found[id].positions),
+		})
+	}
+	return result, nil
+}
+
+// match is a score of document and positions of matched tokens
+type match struct {
+	score     float64
+	positions []int
+}
+
+type matches map[uuid.UUID]*match
+
+// eval return documents of tenant matching query node
+func (i *Index) eval(n node, tenant string) matches {
+	switch n := n.(type) {
+	case *termNode:
+		return i.evalTerm(n, tenant)
+	case *orNode:
+		result := make(matches)
+		for _, child := range n.children {
+			for id, m := range i.eval(child, tenant) {
+				merge(result, id, m)
+			}
+		}
+		return result
+	case *andNode:
+		var result matches
+		var excluded []node
+		for _, child := range n.children {
+			if not, ok := child.(*notNode); ok {
+				excluded = append(excluded, not.child)
+				continue
+			}
+			found := i.eval(child, tenant)
+			if result == nil {
+				result = found
+				continue
+			}
+			for id, m := range result {
+				if other, ok := found[id]; ok {
+					m.score += other.score
+					m.positions = append(m.positions, other.positions...)
+				} else {
+					delete(result, id)
+				}
+			}
+		}
+		if result == nil {
+			result = i.all(tenant)
+		}
+		for _, child := range excluded {
+			for id := range i.eval(child, tenant) {
+				delete(result, id)
+			}
+		}
+		return result
+	case *notNode:
+		result := i.all(tenant)
+		for id := range i.eval(n.child, tenant) {
+			delete(result, id)
+		}
+		return result
+	}
+	return nil
+}
+
+func merge(result matches, id uuid.UUID, m *match) {
+	if current, ok := result[id]; ok {
+		current.score += m.score
+		current.positions = append(current.positions, m.positions...)
+		return
+	}
+	result[id] = m
+}
+
+// all return every document of tenant with zero score
+func (i *Index) all(tenant string) matches {
+	result := make(matches)
+	for id, doc := range i.docs {
+		if doc.tenant == tenant {
+			result[id] = &match{}
+		}
+	}
+	return result
+}
+
+// evalTerm return documents containing stems of term in a row, scored by BM25 of the whole term
+func (i *Index) evalTerm(t *termNode, tenant string) matches {
+	// positions of every token of term in documents of tenant
+	lists := make([]postings, len(t.tokens))
+	for k, token := range t.tokens {
+		if t.prefix && k == len(t.tokens)-1 {
+			lists[k] = i.prefixed(token.Word, tenant)
+		} else {
+			lists[k] = i.stems[token.Stem]
+		}
+	}
+
+	found := make(map[uuid.UUID][]int)
+	for id, first := range lists[0] {
+		if i.docs[id].tenant != tenant {
+			continue
+		}
+		var positions []int
+		for _, start := range first {
+			phrase := true
+			for k := 1; k < len(lists) && phrase; k++ {
+				_, phrase = slices.BinarySearch(lists[k][id], start+k)
+			}
+			if phrase {
+				for k := range lists {
+					positions = append(positions, start+k)
+				}
+			}
+		}
+		if len(positions) > 0 {
+			found[id] = positions
+		}
+	}
+
+	result := make(matches)
+	c := i.tenants[tenant]
+	for id, positions := range found {
+		tf := float64(len(positions) / len(lists))
+		length := float64(len(i.docs[id].tokens))
+		avgLength := float64(c.length) / float64(c.docs)
+		idf := math.Log(1 + (float64(c.docs-len(found))+0.5)/(float64(len(found))+0.5))
+		result[id] = &match{
+			score:     idf * tf * (k1 + 1) / (tf + k1*(1-b+b*length/avgLength)),
+			positions: positions,
+		}
+	}
+	return result
+}
+
+// prefixed return positions of words starting with prefix in documents of tenant
+func (i *Index) prefixed(prefix, tenant string) postings {
+	result := make(postings)
+	for word, p := range i.words {
+		if !strings.HasPrefix(word, prefix) {
+			continue
+		}
+		for id, positions := range p {
+			if i.docs[id].tenant == tenant {
+				result[id] = append(result[id], positions...)
+			}
+		}
+	}
+	for id := range result {
+		slices.Sort(result[id])
+	}
+	return result
+}
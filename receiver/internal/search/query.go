@@ -0,0 +1,252 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrEmptyQuery = errors.New("query is empty")
+	// ErrQuerySyntax wrap every query parse error
+	ErrQuerySyntax = errors.New("invalid query")
+)
+
+// minPrefixChars is a minimal length of prefix term
+const minPrefixChars = 2
+
+// node of parsed query
+type node interface{}
+
+// termNode match consecutive stems of words of raw text,
+// if prefix the last word match any word starting with it
+type termNode struct {
+	raw    string
+	prefix bool
+	tokens []Token
+}
+
+type andNode struct {
+	children []node
+}
+
+type orNode struct {
+	children []node
+}
+
+type notNode struct {
+	child node
+}
+
+// parser of query grammar:
+//
+//	or     = and { "OR" and }
+//	and    = unary { ["AND"] unary }
+//	unary  = ("NOT" | "-") unary | "(" or ")" | term
+//	term   = ( word | '"' words '"' ) ["*"]
+type parser struct {
+	lexemes []lexeme
+	pos     int
+}
+
+type lexemeKind int
+
+const (
+	lexWord lexemeKind = iota
+	lexPhrase
+	lexAnd
+	lexOr
+	lexNot
+	lexOpen
+	lexClose
+)
+
+type lexeme struct {
+	kind lexemeKind
+	text string
+}
+
+// parseQuery return query tree, leaves are not tokenized yet
+func parseQuery(query string) (node, error) {
+	lexemes, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(lexemes) == 0 {
+		return nil, ErrEmptyQuery
+	}
+	p := &parser{lexemes: lexemes}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lexemes) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrQuerySyntax, p.lexemes[p.pos].text)
+	}
+	return root, nil
+}
+
+func (p *parser) or() (node, error) {
+	first, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	children := []node{first}
+	for p.next(lexOr) {
+		child, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &orNode{children: children}, nil
+}
+
+func (p *parser) and() (node, error) {
+	first, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	children := []node{first}
+	for p.pos < len(p.lexemes) {
+		kind := p.lexemes[p.pos].kind
+		if kind == lexOr || kind == lexClose {
+			break
+		}
+		p.next(lexAnd)
+		child, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	if len(children) == 1 {
+		return first, nil
+	}
+	return &andNode{children: children}, nil
+}
+
+func (p *parser) unary() (node, error) {
+	if p.pos == len(p.lexemes) {
+		return nil, fmt.Errorf("%w: unexpected end of query", ErrQuerySyntax)
+	}
+	current := p.lexemes[p.pos]
+	p.pos++
+	switch current.kind {
+	case lexNot:
+		child, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{child: child}, nil
+	case lexOpen:
+		child, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.next(lexClose) {
+			return nil, fmt.Errorf("%w: missing )", ErrQuerySyntax)
+		}
+		return child, nil
+	case lexWord, lexPhrase:
+		raw, prefix := strings.CutSuffix(current.text, "*")
+		return &termNode{raw: raw, prefix: prefix}, nil
+	}
+	return nil, fmt.Errorf("%w: unexpected %q", ErrQuerySyntax, current.text)
+}
+
+// next skip lexeme of kind, report whether it was there
+func (p *parser) next(kind lexemeKind) bool {
+	if p.pos < len(p.lexemes) && p.lexemes[p.pos].kind == kind {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// lex split query to words, quoted phrases, parentheses and upper-case operators,
+// "-" before a word, phrase or parenthesis is NOT
+func lex(query string) ([]lexeme, error) {
+	var lexemes []lexeme
+	for i := 0; i < len(query); {
+		r, size := utf8.DecodeRuneInString(query[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '(':
+			lexemes = append(lexemes, lexeme{kind: lexOpen, text: "("})
+			i += size
+		case r == ')':
+			lexemes = append(lexemes, lexeme{kind: lexClose, text: ")"})
+			i += size
+		case r == '-' && i+size < len(query) && !unicode.IsSpace(rune(query[i+size])):
+			lexemes = append(lexemes, lexeme{kind: lexNot, text: "-"})
+			i += size
+		case r == '"':
+			end := strings.IndexByte(query[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("%w: unterminated phrase", ErrQuerySyntax)
+			}
+			lexemes = append(lexemes, lexeme{kind: lexPhrase, text: query[i+1 : i+1+end]})
+			i += end + 2
+		default:
+			end := strings.IndexFunc(query[i:], func(r rune) bool {
+				return unicode.IsSpace(r) || r == '(' || r == ')' || r == '"'
+			})
+			if end < 0 {
+				end = len(query) - i
+			}
+			word := query[i : i+end]
+			switch word {
+			case "AND":
+				lexemes = append(lexemes, lexeme{kind: lexAnd, text: word})
+			case "OR":
+				lexemes = append(lexemes, lexeme{kind: lexOr, text: word})
+			case "NOT":
+				lexemes = append(lexemes, lexeme{kind: lexNot, text: word})
+			default:
+				lexemes = append(lexemes, lexeme{kind: lexWord, text: word})
+			}
+			i += end
+		}
+	}
+	return lexemes, nil
+}
+
+// terms return leaves of query in order
+func terms(root node) []*termNode {
+	switch n := root.(type) {
+	case *andNode:
+		var result []*termNode
+		for _, child := range n.children {
+			result = append(result, terms(child)...)
+		}
+		return result
+	case *orNode:
+		var result []*termNode
+		for _, child := range n.children {
+			result = append(result, terms(child)...)
+		}
+		return result
+	case *notNode:
+		return terms(n.child)
+	}
+	return []*termNode{root.(*termNode)}
+}
+
+// setTokens assign tokens of term text
+func (t *termNode) setTokens(tokens []Token) error {
+	if len(tokens) == 0 {
+		return fmt.Errorf("%w: %q has no words", ErrQuerySyntax, t.raw)
+	}
+	if last := tokens[len(tokens)-1]; t.prefix && utf8.RuneCountInString(last.Word) < minPrefixChars {
+		return fmt.Errorf("%w: prefix %q is shorter than %d chars", ErrQuerySyntax, last.Word, minPrefixChars)
+	}
+	t.tokens = tokens
+	return nil
+}
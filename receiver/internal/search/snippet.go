@@ -0,0 +1,64 @@
+package search
+
+import (
+	"html"
+	"slices"
+	"strings"
+)
+
+const (
+	// snippetTokens is a number of words in snippet
+	snippetTokens = 30
+	// snippetContext is a number of words shown before the first highlighted word
+	snippetContext = 5
+)
+
+// snippet return html escaped window of document text with the most matched tokens,
+// matched tokens are wrapped into <mark>
+func snippet(doc *document, positions []int) string {
+	if len(doc.tokens) == 0 {
+		return ""
+	}
+	slices.Sort(positions)
+	positions = slices.Compact(positions)
+
+	start, best := 0, 0
+	for k, pos := range positions {
+		from := max(pos-snippetContext, 0)
+		count := 0
+		for _, other := range positions[k:] {
+			if other >= from+snippetTokens {
+				break
+			}
+			count++
+		}
+		if count > best {
+			start, best = from, count
+		}
+	}
+	end := min(start+snippetTokens, len(doc.tokens))
+
+	var sb strings.Builder
+	offset := 0
+	if start > 0 {
+		sb.WriteString("…")
+		offset = doc.tokens[start].Start
+	}
+	for pos := start; pos < end; pos++ {
+		token := doc.tokens[pos]
+		sb.WriteString(html.EscapeString(doc.text[offset:token.Start]))
+		word := html.EscapeString(doc.text[token.Start:token.End])
+		if _, marked := slices.BinarySearch(positions, pos); marked {
+			sb.WriteString("<mark>" + word + "</mark>")
+		} else {
+			sb.WriteString(word)
+		}
+		offset = token.End
+	}
+	if end < len(doc.tokens) {
+		sb.WriteString("…")
+	} else {
+		sb.WriteString(html.EscapeString(doc.text[offset:]))
+	}
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,63 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/Critma/textAnalyzer/shared/signature"
+)
+
+// Token is a lower-cased word of text and its stem, Start and End are byte offsets of the word
+type Token struct {
+	Word  string `json:"word"`
+	Stem  string `json:"stem"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// tokenizer split texts to words by language-aware tokenizer of analyzer service,
+// requests are signed with secret
+type tokenizer struct {
+	client *http.Client
+	addr   string
+	secret string
+}
+
+// tokenize return tokens of every text, in order of texts
+func (t *tokenizer) tokenize(ctx context.Context, texts []string) ([][]Token, error) {
+	data, err := json.Marshal(map[string][]string{"texts": texts})
+	if err != nil {
+		return nil, err
+	}
+	analyzerUrl := fmt.Sprintf("http://%s/api/v1/tokenize", t.addr)
+	req, err := http.NewRequestWithContext(ctx, "POST", analyzerUrl, bytes.NewBuffer(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := signature.SignRequest(req, t.secret, data); err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("analyzer service returned status %d", resp.StatusCode)
+	}
+	var output struct {
+		Tokens [][]Token `json:"tokens"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&output); err != nil {
+		return nil, err
+	}
+	if len(output.Tokens) != len(texts) {
+		return nil, fmt.Errorf("analyzer service returned tokens of %d texts, expected %d", len(output.Tokens), len(texts))
+	}
+	return output.Tokens, nil
+}
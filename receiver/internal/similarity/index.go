@@ -0,0 +1,162 @@
+package similarity
+
+import (
+	"cmp"
+	"encoding/binary"
+	"hash/fnv"
+	"math/bits"
+	"receiver/internal/storage"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// SignatureSize is a number of MinHash values in signatures computed by analyzer
+const SignatureSize = 128
+
+// Index is an LSH index of MinHash signatures of analyzed requests
+//
+// signature is split to bands, requests with an equal band are candidates,
+// so pairs with Jaccard similarity s are found with probability 1-(1-s^rows)^bands
+type Index struct {
+	bands   int
+	mu      sync.RWMutex
+	entries map[uuid.UUID]entry
+	buckets map[bucket]map[uuid.UUID]struct{}
+}
+
+type entry struct {
+	tenant    string
+	signature storage.Signature
+}
+
+// bucket is a hash of one band of signature
+type bucket struct {
+	band int
+	hash uint64
+}
+
+// New return empty index, signatures are split to bands of equal size
+func New(bands int) *Index {
+	return &Index{
+		bands:   max(bands, 1),
+		entries: make(map[uuid.UUID]entry),
+		buckets: make(map[bucket]map[uuid.UUID]struct{}),
+	}
+}
+
+// Add index signature of request, signatures not divisible to bands are ignored
+func (i *Index) Add(id uuid.UUID, tenant string, signature storage.Signature) {
+	if !i.valid(signature) {
+		return
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if _, ok := i.entries[id]; ok {
+		return
+	}
+	i.entries[id] = entry{tenant: tenant, signature: signature}
+	for _, b := range i.bucketsOf(signature) {
+		ids, ok := i.buckets[b]
+		if !ok {
+			ids = make(map[uuid.UUID]struct{})
+			i.buckets[b] = ids
+		}
+		ids[id] = struct{}{}
+	}
+}
+
+// Remove drop request from index
+func (i *Index) Remove(id uuid.UUID) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	indexed, ok := i.entries[id]
+	if !ok {
+		return
+	}
+	delete(i.entries, id)
+	for _, b := range i.bucketsOf(indexed.signature) {
+		delete(i.buckets[b], id)
+		if len(i.buckets[b]) == 0 {
+			delete(i.buckets, b)
+		}
+	}
+}
+
+// Similar return up to limit requests of tenant with estimated Jaccard similarity at least minScore,
+// the most similar first, request exclude is skipped
+func (i *Index) Similar(signature storage.Signature, tenant string, exclude uuid.UUID, limit int, minScore float64) []storage.SimilarRequest {
+	if !i.valid(signature) || limit <= 0 {
+		return nil
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	seen := make(map[uuid.UUID]struct{})
+	var matches []storage.SimilarRequest
+	for _, b := range i.bucketsOf(signature) {
+		for id := range i.buckets[b] {
+			if _, ok := seen[id]; ok || id == exclude {
+				continue
+			}
+			seen[id] = struct{}{}
+			candidate := i.entries[id]
+			if candidate.tenant != tenant {
+				continue
+			}
+			match := storage.SimilarRequest{
+				ID:      id,
+				Score:   jaccard(signature.MinHash, candidate.signature.MinHash),
+				SimHash: 1 - float64(bits.OnesCount64(signature.SimHash^candidate.signature.SimHash))/64,
+			}
+			if match.Score >= minScore {
+				matches = append(matches, match)
+			}
+		}
+	}
+	slices.SortFunc(matches, func(a, b storage.SimilarRequest) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.SimHash, a.SimHash); c != 0 {
+			return c
+		}
+		return slices.Compare(a.ID[:], b.ID[:])
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+func (i *Index) valid(signature storage.Signature) bool {
+	return len(signature.MinHash) > 0 && len(signature.MinHash)%i.bands == 0
+}
+
+// bucketsOf return bucket of every band of signature
+func (i *Index) bucketsOf(signature storage.Signature) []bucket {
+	rows := len(signature.MinHash) / i.bands
+	buckets := make([]bucket, i.bands)
+	for band := range buckets {
+		h := fnv.New64a()
+		for _, v := range signature.MinHash[band*rows : (band+1)*rows] {
+			h.Write(binary.LittleEndian.AppendUint32(nil, v))
+		}
+		buckets[band] = bucket{band: band, hash: h.Sum64()}
+	}
+	return buckets
+}
+
+// jaccard return share of equal values of MinHash signatures of equal length
+func jaccard(a, b []uint32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	equal := 0
+	for k := range a {
+		if a[k] == b[k] {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(a))
+}
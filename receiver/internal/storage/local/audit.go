@@ -0,0 +1,35 @@
+package local
+
+import (
+	"receiver/internal/storage"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	auditLog []storage.AuditRecord = make([]storage.AuditRecord, 0)
+	auditMu  sync.RWMutex
+)
+
+type AuditStore struct {
+}
+
+func (s *AuditStore) AddRecord(record storage.AuditRecord) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	auditLog = append(auditLog, record)
+	return nil
+}
+
+func (s *AuditStore) ListRecords(requestID uuid.UUID) ([]storage.AuditRecord, error) {
+	auditMu.RLock()
+	defer auditMu.RUnlock()
+	result := make([]storage.AuditRecord, 0)
+	for _, record := range auditLog {
+		if requestID == uuid.Nil || record.RequestID == requestID {
+			result = append(result, record)
+		}
+	}
+	return result, nil
+}
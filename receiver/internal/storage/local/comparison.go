@@ -0,0 +1,101 @@
+package local
+
+import (
+	"receiver/internal/storage"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	comparisons   map[uuid.UUID]*storage.Comparison = make(map[uuid.UUID]*storage.Comparison)
+	comparisonsMu sync.RWMutex
+)
+
+type ComparisonStore struct {
+}
+
+func (s *ComparisonStore) CreateComparison(input storage.Comparison) (*storage.Comparison, error) {
+	comparisonsMu.Lock()
+	defer comparisonsMu.Unlock()
+	comparison := &input
+	comparison.ID = uuid.New()
+	comparison.Status = storage.InProcess
+	comparison.CreatedAt = time.Now().UTC()
+	comparison.UpdatedAt = comparison.CreatedAt
+	comparison.CompletedAt = time.Time{}
+	comparisons[comparison.ID] = comparison
+	copied := *comparison
+	return &copied, nil
+}
+
+func (s *ComparisonStore) UpdateComparison(id uuid.UUID, status storage.Status, result storage.ComparisonResult) (*storage.Comparison, error) {
+	comparisonsMu.Lock()
+	defer comparisonsMu.Unlock()
+	comparison, ok := comparisons[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	if comparison.Status.IsTerminal() {
+		return nil, storage.ErrStaleUpdate
+	}
+	comparison.Status = status
+	comparison.Result = result
+	if comparison.Redacted {
+		comparison.Result.Passages = nil
+	}
+	comparison.UpdatedAt = time.Now().UTC()
+	if status.IsTerminal() {
+		comparison.CompletedAt = comparison.UpdatedAt
+	}
+	copied := *comparison
+	return &copied, nil
+}
+
+func (s *ComparisonStore) GetComparison(id uuid.UUID) (*storage.Comparison, error) {
+	comparisonsMu.RLock()
+	defer comparisonsMu.RUnlock()
+	comparison, ok := comparisons[id]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	copied := *comparison
+	return &copied, nil
+}
+
+func (s *ComparisonStore) DeleteComparisons(filter storage.ComparisonFilter) (int, error) {
+	comparisonsMu.Lock()
+	defer comparisonsMu.Unlock()
+	deleted := 0
+	for id, comparison := range comparisons {
+		if comparisonMatches(comparison, filter) {
+			delete(comparisons, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (s *ComparisonStore) RedactComparisons(filter storage.ComparisonFilter) (int, error) {
+	comparisonsMu.Lock()
+	defer comparisonsMu.Unlock()
+	redacted := 0
+	for _, comparison := range comparisons {
+		if comparison.Redacted || !comparisonMatches(comparison, filter) {
+			continue
+		}
+		comparison.Redacted = true
+		comparison.Result.Passages = nil
+		comparison.UpdatedAt = time.Now().UTC()
+		redacted++
+	}
+	return redacted, nil
+}
+
+func comparisonMatches(comparison *storage.Comparison, filter storage.ComparisonFilter) bool {
+	if filter.RequestID != uuid.Nil && comparison.Requests[0] != filter.RequestID && comparison.Requests[1] != filter.RequestID {
+		return false
+	}
+	return filter.CreatedBefore.IsZero() || comparison.CreatedAt.Before(filter.CreatedBefore)
+}
@@ -0,0 +1,35 @@
+package local
+
+import (
+	"receiver/internal/storage"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	deliveries []storage.WebhookDelivery = make([]storage.WebhookDelivery, 0)
+	deliveryMu sync.RWMutex
+)
+
+type DeliveryStore struct {
+}
+
+func (s *DeliveryStore) AddDelivery(delivery storage.WebhookDelivery) error {
+	deliveryMu.Lock()
+	defer deliveryMu.Unlock()
+	deliveries = append(deliveries, delivery)
+	return nil
+}
+
+func (s *DeliveryStore) ListDeliveries(requestID uuid.UUID) ([]storage.WebhookDelivery, error) {
+	deliveryMu.RLock()
+	defer deliveryMu.RUnlock()
+	result := make([]storage.WebhookDelivery, 0)
+	for _, delivery := range deliveries {
+		if delivery.RequestID == requestID {
+			result = append(result, delivery)
+		}
+	}
+	return result, nil
+}
@@ -2,51 +2,195 @@ package local
 
 import (
 	"receiver/internal/storage"
+	"slices"
+	"sort"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 func New() storage.Store {
 	return storage.Store{
-		Requests: &RequestStore{},
+		Requests:    &RequestStore{},
+		Comparisons: &ComparisonStore{},
+		Audit:       &AuditStore{},
+		Deliveries:  &DeliveryStore{},
 	}
 }
 
-var store []*storage.TextRequest = make([]*storage.TextRequest, 0)
+var (
+	// store is ordered by CreatedAt, guarded by mu
+	store []*storage.TextRequest = make([]*storage.TextRequest, 0)
+	mu    sync.RWMutex
+)
 
 type RequestStore struct {
 }
 
-func (s *RequestStore) CreateRequest(text string) (*storage.TextRequest, error) {
-	if text == "" {
+func (s *RequestStore) CreateRequest(input storage.TextRequest) (*storage.TextRequest, error) {
+	if input.Text == "" && input.Blob == "" {
 		return nil, storage.ErrEmptyText
 	}
 
-	request := &storage.TextRequest{
-		ID:     uuid.New(),
-		Text:   text,
-		Status: storage.InProcess,
+	mu.Lock()
+	defer mu.Unlock()
+	request := &input
+	request.ID = uuid.New()
+	if request.Status == "" {
+		request.Status = storage.InProcess
+	}
+	request.CreatedAt = nextCreatedAt()
+	request.UpdatedAt = request.CreatedAt
+	request.CompletedAt = time.Time{}
+	if request.Status.IsTerminal() {
+		request.CompletedAt = request.CreatedAt
 	}
+	request.Revision = 1
 	store = append(store, request)
-	return request, nil
+	return snapshot(request), nil
 }
 
 func (s *RequestStore) UpdateRequest(id uuid.UUID, status storage.Status, analyze storage.AnalyzeResult) (*storage.TextRequest, error) {
+	mu.Lock()
+	defer mu.Unlock()
 	for _, request := range store {
 		if request.ID == id {
+			if request.Status.IsTerminal() {
+				return nil, storage.ErrStaleUpdate
+			}
+			if request.Anonymized() {
+				return nil, storage.ErrAnonymized
+			}
 			request.Status = status
 			request.Analyze = analyze
-			return request, nil
+			request.UpdatedAt = time.Now().UTC()
+			if status.IsTerminal() {
+				request.CompletedAt = request.UpdatedAt
+			}
+			request.Revision++
+			return snapshot(request), nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (s *RequestStore) UpdateProgress(id uuid.UUID, progress int) (*storage.TextRequest, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, request := range store {
+		if request.ID == id {
+			if request.Status.IsTerminal() {
+				return nil, storage.ErrStaleUpdate
+			}
+			request.Progress = progress
+			request.UpdatedAt = time.Now().UTC()
+			request.Revision++
+			return snapshot(request), nil
 		}
 	}
 	return nil, storage.ErrNotFound
 }
 
 func (s *RequestStore) GetRequest(id uuid.UUID) (*storage.TextRequest, error) {
+	mu.RLock()
+	defer mu.RUnlock()
 	for _, request := range store {
 		if request.ID == id {
+			return snapshot(request), nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+func (s *RequestStore) FindAnalyzed(tenant, contentHash string, normalize []string, analyzerVersion string) (*storage.TextRequest, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	for i := len(store) - 1; i >= 0; i-- {
+		request := store[i]
+		if request.Tenant == tenant && request.ContentHash == contentHash && request.Status == storage.Success &&
+			request.Analyze.AnalyzerVersion == analyzerVersion && slices.Equal(request.Normalize, normalize) {
+			return snapshot(request), nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+// ListRequests return copies of matched requests, so callers may read them without lock
+//
+// store is in creation order, so scan starts at query.After found by binary search
+func (s *RequestStore) ListRequests(query storage.Query) ([]*storage.TextRequest, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	result := make([]*storage.TextRequest, 0, min(query.Limit, len(store)))
+	full := func() bool { return query.Limit > 0 && len(result) == query.Limit }
+	if query.Desc {
+		// requests before cursor are a prefix of store
+		end := sort.Search(len(store), func(i int) bool { return !query.Follows(store[i]) })
+		for i := end - 1; i >= 0 && !full(); i-- {
+			if query.Match(store[i]) {
+				result = append(result, snapshot(store[i]))
+			}
+		}
+		return result, nil
+	}
+	// requests after cursor are a suffix of store
+	start := sort.Search(len(store), func(i int) bool { return query.Follows(store[i]) })
+	for i := start; i < len(store) && !full(); i++ {
+		if query.Match(store[i]) {
+			result = append(result, snapshot(store[i]))
+		}
+	}
+	return result, nil
+}
+
+func (s *RequestStore) DeleteRequest(id uuid.UUID) (*storage.TextRequest, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	for i, request := range store {
+		if request.ID == id {
+			store = slices.Delete(store, i, i+1)
 			return request, nil
 		}
 	}
 	return nil, storage.ErrNotFound
 }
+
+func (s *RequestStore) AnonymizeRequest(id uuid.UUID) (*storage.TextRequest, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, request := range store {
+		if request.ID == id {
+			request.Text = ""
+			request.Blob = ""
+			request.Filename = ""
+			request.ContentHash = ""
+			request.Analyze.Signature = storage.Signature{}
+			request.Analyze.TopWords = nil
+			request.Analyze.Similar = nil
+			request.UpdatedAt = time.Now().UTC()
+			request.Revision++
+			return snapshot(request), nil
+		}
+	}
+	return nil, storage.ErrNotFound
+}
+
+// snapshot return copy of stored request, safe to read after mu is released
+func snapshot(request *storage.TextRequest) *storage.TextRequest {
+	copied := *request
+	return &copied
+}
+
+// nextCreatedAt return current time, strictly after the last stored request
+//
+// keeps store ordered and cursors unique, must be called under mu
+func nextCreatedAt() time.Time {
+	now := time.Now().UTC()
+	if len(store) > 0 {
+		if last := store[len(store)-1].CreatedAt; !now.After(last) {
+			now = last.Add(time.Nanosecond)
+		}
+	}
+	return now
+}
@@ -1,12 +1,52 @@
 package storage
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type TextRequest struct {
-	ID      uuid.UUID
-	Text    string
-	Status  Status
-	Analyze AnalyzeResult
+	ID uuid.UUID
+	// Tenant own request, empty if created without api key
+	Tenant   string
+	Text     string
+	Language string
+	Tags     []string
+	Status   Status
+	Analyze  AnalyzeResult
+	// Blob is an id of large document in blob store, Text is empty then
+	Blob string
+	// Size of document in bytes
+	Size int64
+	// Filename and Format of uploaded file text was extracted from
+	Filename string
+	Format   string
+	// Encoding of uploaded text before it was transcoded to UTF-8
+	Encoding string
+	// Normalize are normalization steps applied by analyzer before analysis, in canonical order
+	Normalize []string
+	// Progress is a percentage of analyzed document, reported by analyzer while request is in process
+	Progress int
+	// CallbackURL is notified when request reach terminal status
+	CallbackURL string
+	// ContentHash is normalize.Steps.Hash of Text with Normalize steps,
+	// hex SHA-256 of content for documents posted as blobs
+	ContentHash string
+	// DuplicateOf is the request whose analyze was reused, uuid.Nil if analyzed itself
+	DuplicateOf uuid.UUID
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	// CompletedAt is set when request reach Success or Failed
+	CompletedAt time.Time
+	// Revision is incremented on every change, a copy with lower revision is stale
+	Revision uint64
+}
+
+// Anonymized report whether text of request was dropped, requests are created with text or blob
+func (r *TextRequest) Anonymized() bool {
+	return r.Text == "" && r.Blob == ""
 }
 
 type AnalyzeResult struct {
@@ -14,6 +54,39 @@ type AnalyzeResult struct {
 	CharCount         int
 	SentenceCount     int
 	AverageWordLength float64
+	UniqueWordCount   int
+	// TopWords are the most frequent lower-cased words
+	TopWords []WordFrequency
+	// Normalization steps applied by analyzer
+	Normalization []string
+	// Signature of text, empty if text has no words
+	Signature Signature
+	// Similar are the most similar requests of the same tenant analyzed before this one
+	Similar []SimilarRequest
+	// AnalyzerVersion produced the result
+	AnalyzerVersion string
+}
+
+// Signature is a fingerprint of text computed by analyzer
+type Signature struct {
+	// MinHash of word shingles
+	MinHash []uint32
+	// SimHash of words weighted by frequency
+	SimHash uint64
+}
+
+// SimilarRequest is a near-duplicate of analyzed text
+type SimilarRequest struct {
+	ID uuid.UUID
+	// Score is an estimated Jaccard similarity of word shingles
+	Score float64
+	// SimHash is a share of equal bits of SimHash
+	SimHash float64
+}
+
+type WordFrequency struct {
+	Word  string
+	Count int
 }
 
 type Status string
@@ -23,3 +96,86 @@ var (
 	Success   Status = "success"
 	Failed    Status = "failed"
 )
+
+// IsTerminal report whether status is final and will not change
+func (s Status) IsTerminal() bool {
+	return s == Success || s == Failed
+}
+
+// Comparison is a request of similarity of two texts, compared by analyzer
+//
+// texts are sent to analyzer on creation and not kept
+type Comparison struct {
+	ID uuid.UUID
+	// Tenant own comparison, empty if created without api key
+	Tenant string
+	// Requests whose texts are compared, uuid.Nil for texts submitted directly
+	Requests [2]uuid.UUID
+	Status   Status
+	Result   ComparisonResult
+	// Redacted comparison keeps scores only, passages of texts are dropped
+	Redacted bool
+
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	CompletedAt time.Time
+}
+
+// ComparisonFilter select comparisons of request or created before time, zero fields are not checked
+type ComparisonFilter struct {
+	RequestID     uuid.UUID
+	CreatedBefore time.Time
+}
+
+type ComparisonResult struct {
+	// Jaccard similarity of word shingles
+	Jaccard float64
+	// Cosine similarity of TF-IDF vectors
+	Cosine float64
+	// EditDistance is a word level edit distance divided by length of the longer text
+	EditDistance float64
+	// Passages are the longest common word sequences, longest first
+	Passages []Passage
+	// AnalyzerVersion produced the result
+	AnalyzerVersion string
+}
+
+// Passage is a word sequence found in both compared texts
+type Passage struct {
+	Text  string
+	Words int
+	// LeftOffset and RightOffset are offsets of passage in chars of first and second text
+	LeftOffset  int
+	RightOffset int
+}
+
+// AuditRecord is a trace of erased request, it never contains the text itself
+type AuditRecord struct {
+	ID        uuid.UUID
+	RequestID uuid.UUID
+	Tenant    string
+	Action    AuditAction
+	Reason    string
+	At        time.Time
+}
+
+type AuditAction string
+
+var (
+	Deleted    AuditAction = "deleted"
+	Anonymized AuditAction = "anonymized"
+)
+
+// WebhookDelivery is one attempt to notify request callback url
+type WebhookDelivery struct {
+	ID        uuid.UUID
+	RequestID uuid.UUID
+	URL       string
+	Attempt   int
+	// StatusCode of callback response, 0 if request failed
+	StatusCode int
+	Error      string
+	Success    bool
+	At         time.Time
+	Duration   time.Duration
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Query describe filter and page of ListRequests
+type Query struct {
+	// Tenant own requests, empty match any tenant
+	Tenant   string
+	Statuses []Status
+	Language string
+	// Tags request must have all of them
+	Tags []string
+	// From, To limit CreatedAt to [From, To), zero value is unbounded
+	From time.Time
+	To   time.Time
+	// Desc order from newest to oldest
+	Desc bool
+	// After return only requests placed after cursor, zero value is from start
+	After Cursor
+	Limit int
+}
+
+// Follows report whether request is placed after query.After in query order
+func (q Query) Follows(r *TextRequest) bool {
+	if q.Desc {
+		return q.After.After(r)
+	}
+	return q.After.Before(r)
+}
+
+// Match report whether request satisfy query filters (cursor and limit are ignored)
+func (q Query) Match(r *TextRequest) bool {
+	if q.Tenant != "" && r.Tenant != q.Tenant {
+		return false
+	}
+	if len(q.Statuses) > 0 && !slices.Contains(q.Statuses, r.Status) {
+		return false
+	}
+	if q.Language != "" && r.Language != q.Language {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !slices.Contains(r.Tags, tag) {
+			return false
+		}
+	}
+	if !q.From.IsZero() && r.CreatedAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !r.CreatedAt.Before(q.To) {
+		return false
+	}
+	return true
+}
+
+// Cursor is a position in the creation ordered list of requests
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// CursorOf return cursor pointing at request
+func CursorOf(r *TextRequest) Cursor {
+	return Cursor{CreatedAt: r.CreatedAt, ID: r.ID}
+}
+
+// IsZero report whether cursor points at start of list
+func (c Cursor) IsZero() bool {
+	return c.CreatedAt.IsZero() && c.ID == uuid.Nil
+}
+
+// Before report whether cursor is placed before request in creation order
+func (c Cursor) Before(r *TextRequest) bool {
+	return c.IsZero() || c.compare(r) < 0
+}
+
+// After report whether cursor is placed after request in creation order
+func (c Cursor) After(r *TextRequest) bool {
+	return c.IsZero() || c.compare(r) > 0
+}
+
+// compare cursor with request position by CreatedAt, then by ID
+func (c Cursor) compare(r *TextRequest) int {
+	if cmp := c.CreatedAt.Compare(r.CreatedAt); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(c.ID.String(), r.ID.String())
+}
+
+// String return opaque url-safe cursor representation
+func (c Cursor) String() string {
+	if c.IsZero() {
+		return ""
+	}
+	raw := fmt.Sprintf("%d:%s", c.CreatedAt.UnixNano(), c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decode cursor from String representation, empty string is a zero cursor
+func ParseCursor(s string) (Cursor, error) {
+	if s == "" {
+		return Cursor{}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{CreatedAt: time.Unix(0, n).UTC(), ID: parsedID}, nil
+}
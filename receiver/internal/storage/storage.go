@@ -8,13 +8,53 @@ import (
 
 type Store struct {
 	Requests interface {
-		CreateRequest(text string) (*TextRequest, error)
+		// CreateRequest save copy of request with new ID and timestamps,
+		// status is InProcess unless request status is set, ErrEmptyText if there is neither text nor blob
+		CreateRequest(request TextRequest) (*TextRequest, error)
+		// UpdateRequest set status and analyze, ErrStaleUpdate if request status is already terminal,
+		// ErrAnonymized if request text was dropped
 		UpdateRequest(id uuid.UUID, status Status, analyze AnalyzeResult) (*TextRequest, error)
+		// UpdateProgress set analysis progress, ErrStaleUpdate if request status is already terminal
+		UpdateProgress(id uuid.UUID, progress int) (*TextRequest, error)
 		GetRequest(id uuid.UUID) (*TextRequest, error)
+		// FindAnalyzed return the latest successful request of tenant with content hash and normalization analyzed by analyzerVersion
+		FindAnalyzed(tenant, contentHash string, normalize []string, analyzerVersion string) (*TextRequest, error)
+		// ListRequests return up to query.Limit requests matching query,
+		// ordered by creation time (descending if query.Desc) and placed after query.After
+		ListRequests(query Query) ([]*TextRequest, error)
+		// DeleteRequest remove request, return removed one
+		DeleteRequest(id uuid.UUID) (*TextRequest, error)
+		// AnonymizeRequest drop request text, blob reference, filename, text signature, top words and similar requests,
+		// keep status and the rest of analyze result
+		AnonymizeRequest(id uuid.UUID) (*TextRequest, error)
+	}
+	Comparisons interface {
+		// CreateComparison save copy of comparison with new ID and timestamps, status is InProcess
+		CreateComparison(comparison Comparison) (*Comparison, error)
+		// UpdateComparison set status and result, ErrStaleUpdate if comparison status is already terminal
+		UpdateComparison(id uuid.UUID, status Status, result ComparisonResult) (*Comparison, error)
+		GetComparison(id uuid.UUID) (*Comparison, error)
+		// DeleteComparisons remove comparisons matching filter, return number of removed ones
+		DeleteComparisons(filter ComparisonFilter) (int, error)
+		// RedactComparisons drop passages of comparisons matching filter, also from results saved later,
+		// return number of newly redacted ones
+		RedactComparisons(filter ComparisonFilter) (int, error)
+	}
+	Audit interface {
+		AddRecord(record AuditRecord) error
+		// ListRecords return records of request, or all records if requestID is uuid.Nil
+		ListRecords(requestID uuid.UUID) ([]AuditRecord, error)
+	}
+	Deliveries interface {
+		AddDelivery(delivery WebhookDelivery) error
+		ListDeliveries(requestID uuid.UUID) ([]WebhookDelivery, error)
 	}
 }
 
 var (
-	ErrEmptyText = errors.New("empty text")
-	ErrNotFound  = errors.New("request not found")
+	ErrEmptyText     = errors.New("empty text")
+	ErrNotFound      = errors.New("request not found")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrStaleUpdate   = errors.New("request is already completed")
+	ErrAnonymized    = errors.New("request is anonymized")
 )
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"receiver/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	SignatureHeader = "X-Signature-256"
+	TimestampHeader = "X-Webhook-Timestamp"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	queueSize  = 100
+	workersNum = 4
+	// maxBackoff bound wait between attempts, however many of them are configured
+	maxBackoff = 10 * time.Minute
+)
+
+// Dispatcher deliver analyze results to request callback urls
+type Dispatcher struct {
+	store       storage.Store
+	client      *http.Client
+	secret      []byte
+	maxAttempts int
+	backoff     time.Duration
+	jobs        chan storage.TextRequest
+}
+
+// New return dispatcher, payloads are signed with secret if it is not empty
+//
+// failed delivery is retried up to maxAttempts times, waiting backoff doubled on each attempt up to maxBackoff
+func New(store storage.Store, client *http.Client, secret string, maxAttempts int, backoff time.Duration) *Dispatcher {
+	return &Dispatcher{
+		store:       store,
+		client:      client,
+		secret:      []byte(secret),
+		maxAttempts: max(maxAttempts, 1),
+		backoff:     backoff,
+		jobs:        make(chan storage.TextRequest, queueSize),
+	}
+}
+
+// Run start delivery workers, return when ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	for range workersNum {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case request := <-d.jobs:
+					d.deliver(ctx, request)
+				}
+			}
+		}()
+	}
+	<-ctx.Done()
+}
+
+// Enqueue schedule delivery of request result, do nothing if request has no callback url
+func (d *Dispatcher) Enqueue(request storage.TextRequest) {
+	if request.CallbackURL == "" {
+		return
+	}
+	select {
+	case d.jobs <- request:
+	default:
+		log.Error().Str("event", "webhook").Str("requestID", request.ID.String()).Msg("delivery queue is full")
+		d.record(storage.WebhookDelivery{RequestID: request.ID, URL: request.CallbackURL, Error: "delivery queue is full"})
+	}
+}
+
+// Payload is a body of callback request
+type Payload struct {
+	ID          uuid.UUID      `json:"id"`
+	Status      storage.Status `json:"status"`
+	Analyze     PayloadAnalyze `json:"analyze,omitzero"`
+	CompletedAt time.Time      `json:"completedAt"`
+}
+
+type PayloadAnalyze struct {
+	WordCount         int     `json:"wordCount,omitempty"`
+	CharCount         int     `json:"charCount,omitempty"`
+	SentenceCount     int     `json:"sentenceCount,omitempty"`
+	AverageWordLength float64 `json:"averageWordLength,omitempty"`
+	UniqueWordCount   int     `json:"uniqueWordCount,omitempty"`
+}
+
+// Sign return hex HMAC-SHA256 of "{timestamp}.{body}"
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, request storage.TextRequest) {
+	body, err := json.Marshal(Payload{
+		ID:     request.ID,
+		Status: request.Status,
+		Analyze: PayloadAnalyze{
+			WordCount:         request.Analyze.WordCount,
+			CharCount:         request.Analyze.CharCount,
+			SentenceCount:     request.Analyze.SentenceCount,
+			AverageWordLength: request.Analyze.AverageWordLength,
+			UniqueWordCount:   request.Analyze.UniqueWordCount,
+		},
+		CompletedAt: request.CompletedAt,
+	})
+	if err != nil {
+		log.Error().Str("event", "webhook").Str("requestID", request.ID.String()).Err(err).Msg("failed to marshal payload")
+		return
+	}
+
+	deliveryID := uuid.New()
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		delivery := d.attempt(ctx, request, deliveryID, body)
+		delivery.Attempt = attempt
+		d.record(delivery)
+		if delivery.Success || !retryable(delivery.StatusCode) || attempt == d.maxAttempts {
+			return
+		}
+
+		// exponential backoff with jitter, shift is checked before it can overflow
+		wait := maxBackoff
+		if shift := attempt - 1; d.backoff <= maxBackoff>>shift {
+			wait = d.backoff << shift
+		}
+		wait += rand.N(wait/2 + 1)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, request storage.TextRequest, deliveryID uuid.UUID, body []byte) (delivery storage.WebhookDelivery) {
+	delivery = storage.WebhookDelivery{
+		ID:        uuid.New(),
+		RequestID: request.ID,
+		URL:       request.CallbackURL,
+		At:        time.Now().UTC(),
+	}
+	defer func() {
+		delivery.Duration = time.Since(delivery.At)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, request.CallbackURL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, deliveryID.String())
+	req.Header.Set(TimestampHeader, timestamp)
+	if len(d.secret) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(d.secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+
+	delivery.StatusCode = resp.StatusCode
+	delivery.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !delivery.Success {
+		delivery.Error = fmt.Sprintf("callback returned status %d", resp.StatusCode)
+	}
+	return delivery
+}
+
+func (d *Dispatcher) record(delivery storage.WebhookDelivery) {
+	if delivery.ID == uuid.Nil {
+		delivery.ID = uuid.New()
+		delivery.At = time.Now().UTC()
+	}
+	if err := d.store.Deliveries.AddDelivery(delivery); err != nil {
+		log.Error().Str("event", "webhook").Str("requestID", delivery.RequestID.String()).Err(err).Msg("failed to record delivery")
+	}
+}
+
+// retryable report whether delivery with status code may succeed later
+//
+// 0 status code is a network error
+func retryable(statusCode int) bool {
+	return statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
@@ -0,0 +1,155 @@
+package routes
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"receiver/internal/auth"
+	"receiver/internal/storage"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	QuotaRemainingHeader = "X-Quota-Remaining"
+	// apiKeyContextKey hold *auth.APIKey of authenticated request
+	apiKeyContextKey = "apiKey"
+)
+
+// authenticate require Authorization: Bearer api key, if auth is enabled
+//
+// key is stored in context, X-Quota-Remaining is set for keys with daily quota
+func (r *Routes) authenticate(c *gin.Context) {
+	if !r.App.Config.Auth.Enabled {
+		c.Next()
+		return
+	}
+	token, ok := bearerToken(c.GetHeader("Authorization"))
+	if !ok {
+		c.Header("WWW-Authenticate", `Bearer realm="receiver"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "api key is required"})
+		return
+	}
+	key, err := r.App.Keys.Lookup(context.Background(), token)
+	if err != nil {
+		if err == auth.ErrInvalidKey {
+			c.Header("WWW-Authenticate", `Bearer realm="receiver", error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+			return
+		}
+		log.Error().Str("handler", "authenticate").Err(err).Msg("api key lookup error")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to check api key"})
+		return
+	}
+	c.Set(apiKeyContextKey, key)
+
+	remaining, err := r.App.Keys.Remaining(context.Background(), key)
+	if err != nil {
+		log.Error().Str("handler", "authenticate").Str("keyID", key.ID.String()).Err(err).Msg("quota lookup error")
+	} else {
+		setQuotaRemaining(c, remaining)
+	}
+	c.Next()
+}
+
+// authenticateAdmin require Authorization: Bearer ADMIN_TOKEN
+func (r *Routes) authenticateAdmin(c *gin.Context) {
+	adminToken := r.App.Config.Auth.AdminToken
+	if adminToken == "" {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin api is disabled, set ADMIN_TOKEN"})
+		return
+	}
+	token, ok := bearerToken(c.GetHeader("Authorization"))
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		c.Header("WWW-Authenticate", `Bearer realm="receiver-admin"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid admin token"})
+		return
+	}
+	c.Next()
+}
+
+// bearerToken return token of Authorization: Bearer header
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// apiKeyOf return api key of authenticated request, nil if auth is disabled
+func apiKeyOf(c *gin.Context) *auth.APIKey {
+	if key, ok := c.Get(apiKeyContextKey); ok {
+		return key.(*auth.APIKey)
+	}
+	return nil
+}
+
+// tenantOf return tenant of authenticated request, empty if auth is disabled
+func tenantOf(c *gin.Context) string {
+	if key := apiKeyOf(c); key != nil {
+		return key.Tenant
+	}
+	return ""
+}
+
+// canAccess report whether request belong to caller tenant
+//
+// requests of other tenants must be reported as not found, so their ids are not disclosed
+func canAccess(c *gin.Context, request *storage.TextRequest) bool {
+	return ownedByCaller(c, request.Tenant)
+}
+
+// ownedByCaller report whether resource of tenant is accessible by caller
+func ownedByCaller(c *gin.Context, tenant string) bool {
+	caller := tenantOf(c)
+	return caller == "" || tenant == caller
+}
+
+// consumeQuota charge chars to caller daily quota, write 429 if it is exceeded
+//
+// quota store errors do not block requests
+func (r *Routes) consumeQuota(c *gin.Context, chars int) bool {
+	key := apiKeyOf(c)
+	if key == nil {
+		return true
+	}
+	remaining, err := r.App.Keys.Consume(context.Background(), key, chars)
+	if err != nil {
+		if err == auth.ErrQuotaExceeded {
+			setQuotaRemaining(c, remaining)
+			log.Error().Str("handler", "handle request").Str("keyID", key.ID.String()).Msg("daily quota exceeded")
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "daily quota of characters is exceeded"})
+			return false
+		}
+		log.Error().Str("handler", "handle request").Str("keyID", key.ID.String()).Err(err).Msg("quota store unavailable, quota is not charged")
+		return true
+	}
+	setQuotaRemaining(c, remaining)
+	return true
+}
+
+// refundQuota return chars charged by consumeQuota to caller quota, after the charged work failed
+func (r *Routes) refundQuota(c *gin.Context, chars int) {
+	key := apiKeyOf(c)
+	if key == nil {
+		return
+	}
+	remaining, err := r.App.Keys.Refund(context.Background(), key, chars)
+	if err != nil {
+		log.Error().Str("handler", "handle request").Str("keyID", key.ID.String()).Err(err).Msg("Failed to refund quota")
+		return
+	}
+	setQuotaRemaining(c, remaining)
+}
+
+// setQuotaRemaining set X-Quota-Remaining, unless quota is unlimited
+func setQuotaRemaining(c *gin.Context, remaining int) {
+	if remaining >= 0 {
+		c.Header(QuotaRemainingHeader, strconv.Itoa(remaining))
+	}
+}
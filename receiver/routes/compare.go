@@ -0,0 +1,240 @@
+package routes
+
+import (
+	"net/http"
+	"receiver/internal/metrics"
+	"receiver/internal/storage"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Critma/textAnalyzer/shared/limits"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// @Summary Compare similarity of two texts
+// @Description Creates a comparison of two texts, or of texts of two requests, computed by the analyzer service: Jaccard similarity of word shingles, cosine similarity of TF-IDF vectors, normalized word edit distance and the longest common passages.
+// @Tags Comparisons
+// @Accept json
+// @Produce json
+// @Param payload body JsonCompareInput true "Two texts or two request ids"
+// @Success 200 {object} IdResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} limits.JsonLimitError
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /compare [post]
+func (r *Routes) handleCompare(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "handleCompare")
+	}()
+	var input JsonCompareInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		if limits.IsTooLarge(err) {
+			log.Error().Str("handler", "handle compare").Err(err).Msg("Request body is too large")
+			limits.WriteTooLarge(c, "Request body is too large", r.App.Config.Limits.MaxBodyBytes, limits.UnitBytes)
+			return
+		}
+		log.Error().Str("handler", "handle compare").Err(err).Msg("Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	pair, ok := r.readTextPair(c, "handle compare", input)
+	if !ok {
+		return
+	}
+
+	comparison, err := r.App.Store.Comparisons.CreateComparison(storage.Comparison{Tenant: tenantOf(c), Requests: pair.requests})
+	if err != nil {
+		r.refundQuota(c, pair.chars)
+		log.Error().Str("handler", "handle compare").Err(err).Msg("Failed to save comparison")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save comparison"})
+		return
+	}
+	limit, err := sendCompareToAnalyzer(r.App, comparison, pair.texts[0], pair.texts[1])
+	if err != nil || limit != nil {
+		r.refundQuota(c, pair.chars)
+		if _, err := r.App.Store.Comparisons.UpdateComparison(comparison.ID, storage.Failed, storage.ComparisonResult{}); err != nil {
+			log.Error().Str("handler", "handle compare").Str("comparisonID", comparison.ID.String()).Err(err).Msg("comparison update error")
+		}
+		if limit != nil {
+			log.Error().Str("handler", "handle compare").Msg("Texts are too long to compare")
+			c.JSON(http.StatusRequestEntityTooLarge, limit)
+			return
+		}
+		log.Error().Str("handler", "handle compare").Err(err).Msg("Failed to send to analyzer")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send to analyzer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, IdResponse{ID: comparison.ID.String(), Status: comparison.Status})
+}
+
+// textPair is two compared texts
+type textPair struct {
+	texts [2]string
+	// requests the texts are taken from, uuid.Nil for texts submitted directly
+	requests [2]uuid.UUID
+	// normalize are normalization steps of requests
+	normalize [2][]string
+	// chars of both texts charged to caller quota
+	chars int
+}
+
+// readTextPair return two texts of input, or texts of two caller requests, and charge them to caller quota,
+// write 4xx or 500 if they can't be compared
+func (r *Routes) readTextPair(c *gin.Context, handler string, input JsonCompareInput) (*textPair, bool) {
+	pair := &textPair{}
+	switch {
+	case len(input.Texts) == 2 && len(input.IDs) == 0:
+		copy(pair.texts[:], input.Texts)
+	case len(input.IDs) == 2 && len(input.Texts) == 0:
+		copy(pair.requests[:], input.IDs)
+		for i, id := range pair.requests {
+			request, ok := r.comparedRequest(c, handler, id)
+			if !ok {
+				return nil, false
+			}
+			pair.texts[i], pair.normalize[i] = request.Text, request.Normalize
+		}
+	default:
+		log.Error().Str("handler", handler).Msg("Invalid compared texts")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either two texts or two request ids"})
+		return nil, false
+	}
+
+	for _, text := range pair.texts {
+		if text == "" {
+			log.Error().Str("handler", handler).Msg("Text cannot be empty")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Text cannot be empty"})
+			return nil, false
+		}
+		count := utf8.RuneCountInString(text)
+		if maxChars := r.App.Config.Limits.MaxTextChars; maxChars > 0 && count > maxChars {
+			log.Error().Str("handler", handler).Msg("Text is too long")
+			limits.WriteTooLarge(c, "Text is too long to compare", int64(maxChars), limits.UnitChars)
+			return nil, false
+		}
+		pair.chars += count
+	}
+	return pair, r.consumeQuota(c, pair.chars)
+}
+
+// comparedRequest return caller request with inline text, write 400 or 404 if it can't be compared
+func (r *Routes) comparedRequest(c *gin.Context, handler string, id uuid.UUID) (*storage.TextRequest, bool) {
+	request, err := r.App.Store.Requests.GetRequest(id)
+	if err == nil && !canAccess(c, request) {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		if err == storage.ErrNotFound {
+			log.Error().Str("handler", handler).Str("requestID", id.String()).Msg("request not found")
+			c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+			return nil, false
+		}
+		log.Error().Str("handler", handler).Str("requestID", id.String()).Err(err).Msg("found request error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get request"})
+		return nil, false
+	}
+	if request.Blob != "" {
+		log.Error().Str("handler", handler).Str("requestID", id.String()).Msg("Document can't be compared")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Large documents can't be compared"})
+		return nil, false
+	}
+	if request.Text == "" {
+		log.Error().Str("handler", handler).Str("requestID", id.String()).Msg("Text is erased")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Text of request " + id.String() + " is erased"})
+		return nil, false
+	}
+	return request, true
+}
+
+// @Summary Get comparison of two texts
+// @Description Returns status of a comparison and, once it is completed, similarity of the texts.
+// @Tags Comparisons
+// @Produce json
+// @Param id path string true "Comparison ID"
+// @Success 200 {object} JsonComparison
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /compare/{id} [get]
+func (r *Routes) getComparison(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "getComparison")
+	}()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Error().Str("handler", "get comparison").Err(err).Msg("Invalid ID")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	comparison, err := r.App.Store.Comparisons.GetComparison(id)
+	if err == nil && !ownedByCaller(c, comparison.Tenant) {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "comparison not found"})
+			return
+		}
+		log.Error().Str("handler", "get comparison").Str("comparisonID", id.String()).Err(err).Msg("found comparison error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comparison"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newJsonComparison(comparison))
+}
+
+// @Summary Update comparison of two texts
+// @Description Saves the comparison result computed by the analyzer service.
+// @Tags Microservices
+// @Accept json
+// @Produce json
+// @Param payload body JsonCompareAnswer true "Comparison status and result"
+// @Param X-Internal-Timestamp header string true "Unix time of signing"
+// @Param X-Internal-Nonce header string true "Unique value of the callback"
+// @Param X-Internal-Signature header string true "sha256=HMAC-SHA256(INTERNAL_SECRET, timestamp.nonce.body)"
+// @Success 200 {object} StatusResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /compare/result [post]
+func (r *Routes) updateComparison(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "updateComparison")
+	}()
+	var answer JsonCompareAnswer
+	if err := c.ShouldBindJSON(&answer); err != nil {
+		log.Error().Str("handler", "update comparison").Err(err).Msg("Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	result := answer.Compare.comparisonResult(answer.AnalyzerVersion)
+	if _, err := r.App.Store.Comparisons.UpdateComparison(answer.ID, answer.Status, result); err != nil {
+		if err == storage.ErrNotFound {
+			log.Error().Str("handler", "update comparison").Str("comparisonID", answer.ID.String()).Err(err).Msg("comparison not found")
+			c.JSON(http.StatusNotFound, gin.H{"error": "comparison not found"})
+			return
+		}
+		if err == storage.ErrStaleUpdate {
+			log.Error().Str("handler", "update comparison").Str("comparisonID", answer.ID.String()).Err(err).Msg("stale update ignored")
+			c.JSON(http.StatusConflict, gin.H{"error": "comparison is already completed"})
+			return
+		}
+		log.Error().Str("handler", "update comparison").Str("comparisonID", answer.ID.String()).Err(err).Msg("comparison update error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "comparison update error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
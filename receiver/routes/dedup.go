@@ -0,0 +1,37 @@
+package routes
+
+import (
+	"receiver/internal/metrics"
+	"receiver/internal/storage"
+
+	"github.com/Critma/textAnalyzer/shared/normalize"
+	"github.com/rs/zerolog/log"
+)
+
+// contentHash return dedup key of text analyzed with normalization steps, the same as analyzer cache key
+func contentHash(text string, steps []string) string {
+	// steps are validated by parseNormalize
+	parsed, _ := normalize.Parse(steps)
+	return parsed.Hash(text)
+}
+
+// findAnalyzed return successful request of tenant with the same content and normalization, analyzed by current analyzer version
+//
+// return nil if there is none or analyzer version is unknown
+func (r *Routes) findAnalyzed(tenant, hash string, normalize []string) *storage.TextRequest {
+	version, err := getAnalyzerVersion(r.App)
+	if err != nil {
+		log.Error().Str("handler", "handle request").Err(err).Msg("Failed to get analyzer version, skip dedup")
+		return nil
+	}
+	analyzed, err := r.App.Store.Requests.FindAnalyzed(tenant, hash, normalize, version)
+	if err != nil {
+		if err != storage.ErrNotFound {
+			log.Error().Str("handler", "handle request").Err(err).Msg("find analyzed error")
+		}
+		metrics.ObserveDedup(false)
+		return nil
+	}
+	metrics.ObserveDedup(true)
+	return analyzed
+}
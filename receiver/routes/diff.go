@@ -0,0 +1,68 @@
+package routes
+
+import (
+	"net/http"
+	"receiver/internal/metrics"
+	"time"
+
+	"github.com/Critma/textAnalyzer/shared/limits"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// @Summary Diff two versions of a text
+// @Description Compares two texts, or texts of two requests, the first is the old version. Returns word and sentence level changes found by Myers algorithm as insert, delete and replace operations, and every analysis metric of both versions with its change.
+// @Tags Comparisons
+// @Accept json
+// @Produce json
+// @Param payload body JsonCompareInput true "Two texts or two request ids, old version first"
+// @Success 200 {object} JsonDiff
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 413 {object} limits.JsonLimitError
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /diff [post]
+func (r *Routes) handleDiff(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "handleDiff")
+	}()
+	var input JsonCompareInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		if limits.IsTooLarge(err) {
+			log.Error().Str("handler", "handle diff").Err(err).Msg("Request body is too large")
+			limits.WriteTooLarge(c, "Request body is too large", r.App.Config.Limits.MaxBodyBytes, limits.UnitBytes)
+			return
+		}
+		log.Error().Str("handler", "handle diff").Err(err).Msg("Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	pair, ok := r.readTextPair(c, "handle diff", input)
+	if !ok {
+		return
+	}
+
+	diff, limit, err := diffFromAnalyzer(r.App, JsonDiffToAnalyzer{
+		Left:           pair.texts[0],
+		Right:          pair.texts[1],
+		LeftNormalize:  pair.normalize[0],
+		RightNormalize: pair.normalize[1],
+	})
+	if err != nil || limit != nil {
+		r.refundQuota(c, pair.chars)
+	}
+	if err != nil {
+		log.Error().Str("handler", "handle diff").Err(err).Msg("Failed to diff in analyzer")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to diff texts"})
+		return
+	}
+	if limit != nil {
+		log.Error().Str("handler", "handle diff").Msg("Texts are too long to diff")
+		c.JSON(http.StatusRequestEntityTooLarge, limit)
+		return
+	}
+	c.JSON(http.StatusOK, diff)
+}
@@ -0,0 +1,103 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"receiver/internal/blob"
+	"receiver/internal/charset"
+	"receiver/internal/metrics"
+	"receiver/internal/storage"
+	"receiver/internal/webhook"
+	"strings"
+	"time"
+
+	"github.com/Critma/textAnalyzer/shared/limits"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// @Summary Create analysis request of a large document
+// @Description Streams raw text of the request body to blob storage, the analyzer reads it by reference and analyzes it in chunks. Use it for texts above MAX_TEXT_CHARS. UTF-16, Windows-1251 and KOI8-R texts are transcoded to UTF-8. Documents are not added to full-text search index.
+// @Tags Requests
+// @Accept plain
+// @Produce json
+// @Param payload body string true "Raw text"
+// @Param language query string false "Language code"
+// @Param tags query string false "Comma separated tags"
+// @Param callbackUrl query string false "Notified with the result when analysis completes"
+// @Param normalize query string false "Comma separated normalization steps"
+// @Success 200 {object} IdResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} limits.JsonLimitError
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /documents [post]
+func (r *Routes) handleDocument(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "handleDocument")
+	}()
+	callbackURL := c.Query("callbackUrl")
+	if callbackURL != "" && !webhook.ValidURL(callbackURL) {
+		log.Error().Str("handler", "handle document").Str("callbackUrl", callbackURL).Msg("Invalid callback url")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "callbackUrl must be an absolute http(s) url of a public host"})
+		return
+	}
+	steps, err := parseNormalize(splitNormalize(c.QueryArray("normalize")))
+	if err != nil {
+		log.Error().Str("handler", "handle document").Err(err).Msg("Invalid normalization")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// text is transcoded to UTF-8 while it is streamed to blob storage
+	body, encoding, err := charset.NewReader(c.Request.Body)
+	var stored *blob.Blob
+	if err == nil {
+		stored, err = r.App.Blobs.Put(body)
+	}
+	if err != nil {
+		if limits.IsTooLarge(err) {
+			log.Error().Str("handler", "handle document").Msg("Document is too large")
+			limits.WriteTooLarge(c, "Document is too large", r.App.Config.Limits.MaxDocumentBytes, limits.UnitBytes)
+			return
+		}
+		if err == blob.ErrEmpty {
+			log.Error().Str("handler", "handle document").Msg("Document cannot be empty")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Document cannot be empty"})
+			return
+		}
+		if errors.Is(err, charset.ErrInvalidUTF8) {
+			log.Error().Str("handler", "handle document").Msg("Invalid encoding")
+			c.JSON(http.StatusBadRequest, gin.H{"error": invalidEncodingMessage})
+			return
+		}
+		log.Error().Str("handler", "handle document").Err(err).Msg("Failed to store document")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return
+	}
+
+	input := storage.TextRequest{
+		Blob:        stored.ID,
+		Size:        stored.Size,
+		Language:    normalizeLanguage(c.Query("language")),
+		Tags:        normalizeTags(strings.Split(c.Query("tags"), ",")),
+		CallbackURL: callbackURL,
+		Encoding:    string(encoding),
+		ContentHash: stored.ContentHash,
+		Normalize:   steps,
+	}
+	request, ok := r.createRequest(c, input, stored.Chars)
+	if !ok {
+		if err := r.App.Blobs.Delete(stored.ID); err != nil {
+			log.Error().Str("handler", "handle document").Str("blob", stored.ID).Err(err).Msg("Failed to delete blob")
+		}
+		return
+	}
+	// duplicate document is already analyzed
+	if !request.Status.IsTerminal() && !r.dispatchRequest(c, request, stored.Chars) {
+		return
+	}
+
+	c.JSON(http.StatusOK, IdResponse{ID: request.ID.String(), Status: request.Status})
+}
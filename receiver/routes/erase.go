@@ -0,0 +1,100 @@
+package routes
+
+import (
+	"net/http"
+	"receiver/internal/metrics"
+	"receiver/internal/retention"
+	"receiver/internal/storage"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// @Summary Delete text analysis request
+// @Description Erases the request from the store, the receiver cache and the analyzer cache. The deletion is recorded in the audit log.
+// @Tags Requests
+// @Produce json
+// @Param id path string true "Unique Request ID"
+// @Success 200 {object} StatusResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /text/{id} [delete]
+func (r *Routes) deleteRequest(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "deleteRequest")
+	}()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Error().Str("handler", "delete request").Err(err).Msg("Invalid ID")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	request, err := r.App.Store.Requests.GetRequest(id)
+	if err == nil && !canAccess(c, request) {
+		err = storage.ErrNotFound
+	}
+	if err == nil {
+		err = retention.Erase(r.App, id, "user request")
+	}
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+			return
+		}
+		log.Error().Str("handler", "delete request").Str("requestID", id.String()).Err(err).Msg("erase error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete request"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// @Summary List audit records of erased requests
+// @Description Returns deletion and anonymization records of the caller tenant, optionally of a single request.
+// @Tags Requests
+// @Produce json
+// @Param requestId query string false "Request ID"
+// @Success 200 {array} JsonAuditRecord
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /audit [get]
+func (r *Routes) listAudit(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "listAudit")
+	}()
+	requestID := uuid.Nil
+	if param := c.Query("requestId"); param != "" {
+		var err error
+		if requestID, err = uuid.Parse(param); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid requestId"})
+			return
+		}
+	}
+
+	records, err := r.App.Store.Audit.ListRecords(requestID)
+	if err != nil {
+		log.Error().Str("handler", "list audit").Err(err).Msg("list records error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit records"})
+		return
+	}
+	tenant := tenantOf(c)
+	result := make([]JsonAuditRecord, 0, len(records))
+	for _, record := range records {
+		if tenant != "" && record.Tenant != tenant {
+			continue
+		}
+		result = append(result, JsonAuditRecord{
+			ID:        record.ID,
+			RequestID: record.RequestID,
+			Action:    record.Action,
+			Reason:    record.Reason,
+			At:        record.At,
+		})
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,222 @@
+package routes
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"receiver/internal/metrics"
+	"receiver/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// exportBatchSize is a number of requests read from store at once
+const exportBatchSize = 100
+
+// exportColumn describe one exported field of TextRequest
+type exportColumn struct {
+	name  string
+	value func(r *storage.TextRequest) any
+}
+
+// exportColumns in default output order
+var exportColumns = []exportColumn{
+	{"id", func(r *storage.TextRequest) any { return r.ID.String() }},
+	{"status", func(r *storage.TextRequest) any { return r.Status }},
+	{"language", func(r *storage.TextRequest) any { return r.Language }},
+	{"tags", func(r *storage.TextRequest) any { return strings.Join(r.Tags, ",") }},
+	{"createdAt", func(r *storage.TextRequest) any { return r.CreatedAt.Format(time.RFC3339Nano) }},
+	{"updatedAt", func(r *storage.TextRequest) any { return r.UpdatedAt.Format(time.RFC3339Nano) }},
+	{"completedAt", func(r *storage.TextRequest) any { return formatOptionalTime(r.CompletedAt) }},
+	{"filename", func(r *storage.TextRequest) any { return r.Filename }},
+	{"format", func(r *storage.TextRequest) any { return r.Format }},
+	{"encoding", func(r *storage.TextRequest) any { return r.Encoding }},
+	{"normalize", func(r *storage.TextRequest) any { return strings.Join(r.Normalize, ",") }},
+	{"text", func(r *storage.TextRequest) any { return r.Text }},
+	{"wordCount", func(r *storage.TextRequest) any { return r.Analyze.WordCount }},
+	{"charCount", func(r *storage.TextRequest) any { return r.Analyze.CharCount }},
+	{"sentenceCount", func(r *storage.TextRequest) any { return r.Analyze.SentenceCount }},
+	{"averageWordLength", func(r *storage.TextRequest) any { return r.Analyze.AverageWordLength }},
+	{"uniqueWordCount", func(r *storage.TextRequest) any { return r.Analyze.UniqueWordCount }},
+}
+
+// formatOptionalTime return RFC3339 time or empty string for zero time
+func formatOptionalTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// parseColumns return columns listed in comma separated names, or all columns if names is empty
+func parseColumns(names string) ([]exportColumn, error) {
+	if names == "" {
+		return exportColumns, nil
+	}
+	var columns []exportColumn
+	for name := range strings.SplitSeq(names, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, column := range exportColumns {
+			if column.name == name {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+// exportWriter write requests in one of export formats
+type exportWriter interface {
+	writeHeader(columns []exportColumn) error
+	writeRecord(cursor string, r *storage.TextRequest, columns []exportColumn) error
+	flush() error
+}
+
+// @Summary Export text analysis requests
+// @Description Streams stored requests as NDJSON or CSV, ordered by creation time. Every record carries a cursor, pass the last received one to resume the export.
+// @Tags Requests
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "Output format: ndjson (default) or csv"
+// @Param columns query string false "Comma separated columns: id,status,language,tags,createdAt,updatedAt,completedAt,text,wordCount,charCount,sentenceCount,averageWordLength"
+// @Param status query string false "Comma separated statuses to export"
+// @Param language query string false "Export requests in language"
+// @Param tag query string false "Comma separated tags, request must have all of them"
+// @Param from query string false "Export requests created at or after (RFC3339)"
+// @Param to query string false "Export requests created before (RFC3339)"
+// @Param cursor query string false "Resume after record with this cursor"
+// @Success 200 {string} string "NDJSON or CSV stream"
+// @Failure 400 {object} ErrorResponse
+// @Router /requests/export [get]
+func (r *Routes) exportRequests(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "exportRequests")
+	}()
+	query, err := parseFilter(c)
+	if err != nil {
+		log.Error().Str("handler", "export requests").Err(err).Msg("Invalid query")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+	columns, err := parseColumns(c.Query("columns"))
+	if err != nil {
+		log.Error().Str("handler", "export requests").Err(err).Msg("Invalid columns")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var w exportWriter
+	switch c.DefaultQuery("format", "ndjson") {
+	case "ndjson":
+		c.Header("Content-Type", "application/x-ndjson")
+		w = &ndjsonWriter{enc: json.NewEncoder(c.Writer), flusher: c.Writer}
+	case "csv":
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		w = &csvWriter{w: csv.NewWriter(c.Writer), flusher: c.Writer}
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be ndjson or csv"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+	if err := w.writeHeader(columns); err != nil {
+		log.Error().Str("handler", "export requests").Err(err).Msg("write header error")
+		return
+	}
+	query.Limit = exportBatchSize
+	for {
+		// stop when client went away
+		if c.Request.Context().Err() != nil {
+			return
+		}
+		batch, err := r.App.Store.Requests.ListRequests(query)
+		if err != nil {
+			// headers already sent, client resume with last received cursor
+			log.Error().Str("handler", "export requests").Err(err).Msg("list requests error")
+			return
+		}
+		for _, request := range batch {
+			query.After = storage.CursorOf(request)
+			if err := w.writeRecord(query.After.String(), request, columns); err != nil {
+				log.Error().Str("handler", "export requests").Err(err).Msg("write record error")
+				return
+			}
+		}
+		if err := w.flush(); err != nil {
+			log.Error().Str("handler", "export requests").Err(err).Msg("flush error")
+			return
+		}
+		if len(batch) < exportBatchSize {
+			return
+		}
+	}
+}
+
+type ndjsonWriter struct {
+	enc     *json.Encoder
+	flusher http.Flusher
+}
+
+func (w *ndjsonWriter) writeHeader(columns []exportColumn) error {
+	return nil
+}
+
+// writeRecord write one json object per line, cursor is always included
+func (w *ndjsonWriter) writeRecord(cursor string, r *storage.TextRequest, columns []exportColumn) error {
+	record := make(map[string]any, len(columns)+1)
+	record["cursor"] = cursor
+	for _, column := range columns {
+		record[column.name] = column.value(r)
+	}
+	return w.enc.Encode(record)
+}
+
+func (w *ndjsonWriter) flush() error {
+	w.flusher.Flush()
+	return nil
+}
+
+type csvWriter struct {
+	w       *csv.Writer
+	flusher http.Flusher
+}
+
+// writeHeader write column names, cursor is always the first column
+func (w *csvWriter) writeHeader(columns []exportColumn) error {
+	header := []string{"cursor"}
+	for _, column := range columns {
+		header = append(header, column.name)
+	}
+	return w.w.Write(header)
+}
+
+func (w *csvWriter) writeRecord(cursor string, r *storage.TextRequest, columns []exportColumn) error {
+	record := []string{cursor}
+	for _, column := range columns {
+		switch v := column.value(r).(type) {
+		case float64:
+			record = append(record, strconv.FormatFloat(v, 'f', -1, 64))
+		default:
+			record = append(record, fmt.Sprint(v))
+		}
+	}
+	return w.w.Write(record)
+}
+
+func (w *csvWriter) flush() error {
+	w.w.Flush()
+	w.flusher.Flush()
+	return w.w.Error()
+}
@@ -3,11 +3,14 @@ package routes
 import (
 	"context"
 	"net/http"
-	"receiver/cache"
+	"receiver/internal/events"
 	"receiver/internal/metrics"
 	"receiver/internal/storage"
+	"receiver/internal/webhook"
 	"time"
+	"unicode/utf8"
 
+	"github.com/Critma/textAnalyzer/shared/limits"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
@@ -19,8 +22,11 @@ import (
 // @Accept json
 // @Produce json
 // @Param payload body JsonTextInput true "Input text to create an analysis request"
+// @Param Idempotency-Key header string false "Repeated requests with the same key return the original request"
 // @Success 200 {object} IdResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 413 {object} limits.JsonLimitError
 // @Failure 500 {object} ErrorResponse
 // @Router /text [post]
 func (r *Routes) handleCreate(c *gin.Context) {
@@ -28,37 +34,130 @@ func (r *Routes) handleCreate(c *gin.Context) {
 	defer func() {
 		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "handleCreate")
 	}()
+	req, ok := r.bindTextInput(c)
+	if !ok {
+		return
+	}
+	key, handled := r.reserveIdempotencyKey(c, req)
+	if handled {
+		return
+	}
+	chars := utf8.RuneCountInString(req.Text)
+	request, ok := r.createRequest(c, newTextRequest(req), chars)
+	if !ok {
+		r.releaseIdempotencyKey(key)
+		return
+	}
+	// duplicate text is already analyzed
+	if !request.Status.IsTerminal() && !r.dispatchRequest(c, request, chars) {
+		r.releaseIdempotencyKey(key)
+		return
+	}
+	r.completeIdempotencyKey(key, req, request.ID)
+
+	c.JSON(http.StatusOK, IdResponse{ID: request.ID.String(), Status: request.Status})
+}
+
+// bindTextInput parse and validate JsonTextInput, write 400 or 413 on error
+func (r *Routes) bindTextInput(c *gin.Context) (JsonTextInput, bool) {
 	var req JsonTextInput
-	//TODO limit read body
+	// body size is limited by limitBody middleware
 	if err := c.ShouldBindJSON(&req); err != nil {
+		if limits.IsTooLarge(err) {
+			log.Error().Str("handler", "handle request").Err(err).Msg("Request body is too large")
+			limits.WriteTooLarge(c, "Request body is too large, upload large texts with POST /documents", r.App.Config.Limits.MaxBodyBytes, limits.UnitBytes)
+			return req, false
+		}
 		log.Error().Str("handler", "handle request").Err(err).Msg("Invalid request body")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-		return
+		return req, false
 	}
 
 	if req.Text == "" {
 		log.Error().Str("handler", "handle request").Msg("Text cannot be empty")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Text cannot be empty"})
-		return
+		return req, false
+	}
+	steps, err := parseNormalize(req.Normalize)
+	if err != nil {
+		log.Error().Str("handler", "handle request").Strs("normalize", req.Normalize).Err(err).Msg("Invalid normalization")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return req, false
+	}
+	req.Normalize = steps
+	if maxChars := r.App.Config.Limits.MaxTextChars; maxChars > 0 && utf8.RuneCountInString(req.Text) > maxChars {
+		log.Error().Str("handler", "handle request").Msg("Text is too long")
+		limits.WriteTooLarge(c, "Text is too long, upload large texts with POST /documents", int64(maxChars), limits.UnitChars)
+		return req, false
+	}
+
+	if req.CallbackURL != "" && !webhook.ValidURL(req.CallbackURL) {
+		log.Error().Str("handler", "handle request").Str("callbackUrl", req.CallbackURL).Msg("Invalid callback url")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "callbackUrl must be an absolute http(s) url of a public host"})
+		return req, false
+	}
+	return req, true
+}
+
+// newTextRequest return request of inline text
+func newTextRequest(req JsonTextInput) storage.TextRequest {
+	return storage.TextRequest{
+		Text:        req.Text,
+		Language:    normalizeLanguage(req.Language),
+		Tags:        normalizeTags(req.Tags),
+		CallbackURL: req.CallbackURL,
+		ContentHash: contentHash(req.Text, req.Normalize),
+		Normalize:   req.Normalize,
+	}
+}
+
+// createRequest charge chars to caller quota and save request of caller tenant to storage, write 429 or 500 on error
+//
+// if the same text is already analyzed, request is saved as success with copied analyze
+func (r *Routes) createRequest(c *gin.Context, input storage.TextRequest, chars int) (*storage.TextRequest, bool) {
+	if !r.consumeQuota(c, chars) {
+		return nil, false
+	}
+	input.Tenant = tenantOf(c)
+	// requests of other tenants are never reused, so their texts are not disclosed by timing or status
+	if analyzed := r.findAnalyzed(input.Tenant, input.ContentHash, input.Normalize); analyzed != nil {
+		input.Status = storage.Success
+		input.Analyze = analyzed.Analyze
+		input.Analyze.Similar = r.similarTo(input.Analyze.Signature, input.Tenant, uuid.Nil)
+		input.DuplicateOf = analyzed.ID
 	}
 
-	// Save to storage
-	request, err := r.App.Store.Requests.CreateRequest(req.Text)
+	request, err := r.App.Store.Requests.CreateRequest(input)
 	if err != nil {
+		r.refundQuota(c, chars)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save text"})
-		return
+		return nil, false
+	}
+	r.App.Cache.Set(context.Background(), request.ID.String(), *request)
+	if request.Status == storage.Success {
+		r.App.Similar.Add(request.ID, request.Tenant, request.Analyze.Signature)
+		r.App.Search.Enqueue(*request)
+	}
+	if request.Status.IsTerminal() {
+		r.App.Webhooks.Enqueue(*request)
 	}
+	return request, true
+}
 
-	// Send to analyzer service
-	if err := sendToAnalyzer(r.App, request.ID, req.Text); err != nil {
+// dispatchRequest send request to analyzer service, on error mark request failed,
+// refund chars charged for it and write 500
+func (r *Routes) dispatchRequest(c *gin.Context, request *storage.TextRequest, chars int) bool {
+	if err := sendToAnalyzer(r.App, request); err != nil {
+		r.refundQuota(c, chars)
 		// Update status to failed
-		r.App.Store.Requests.UpdateRequest(request.ID, storage.Failed, storage.AnalyzeResult{})
+		if _, err := r.updateStatus(request.ID, storage.Failed, storage.AnalyzeResult{}); err != nil {
+			log.Error().Str("handler", "handle request").Str("requestID", request.ID.String()).Err(err).Msg("request update error")
+		}
 		log.Error().Str("handler", "handle request").Err(err).Msg("Failed to send to analyzer")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send to analyzer"})
-		return
+		return false
 	}
-
-	c.JSON(http.StatusOK, gin.H{"id": request.ID})
+	return true
 }
 
 // @Summary Get status of a specific text analysis request
@@ -67,6 +166,7 @@ func (r *Routes) handleCreate(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Unique Request ID"
+// @Param wait query string false "Long polling: block up to this duration (e.g. 30s, max 60s) while request is in process"
 // @Success 200 {object} JsonRequest
 // @Success 200 {object} JsonStatusOnlyOutput
 // @Failure 400 {object} ErrorResponse
@@ -84,43 +184,86 @@ func (r *Routes) getStatus(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
 		return
 	}
+	wait, err := parseWait(c.Query("wait"))
+	if err != nil {
+		log.Error().Str("handler", "get status").Err(err).Msg("Invalid wait")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wait"})
+		return
+	}
+	var waiter *events.Subscription
+	if wait > 0 {
+		// subscribe before reading status, so no change is lost in between
+		waiter = r.App.Events.Subscribe(id)
+		defer waiter.Close()
+	}
 
-	var result *storage.TextRequest
-	// check is request cached
-	result = cache.GetFromRedis(r.App.Redis, id)
-
-	if result == nil {
-		// request not cached, get from store
-		result, err = r.App.Store.Requests.GetRequest(id)
+	// check is request cached, else get from store once for concurrent callers
+	cached, err := r.App.Cache.Load(context.Background(), id.String(), func() (storage.TextRequest, error) {
+		request, err := r.App.Store.Requests.GetRequest(id)
 		if err != nil {
-			if err == storage.ErrNotFound {
-				log.Error().Str("handler", "get status").Str("requestID", id.String()).Err(err).Msg("request not found")
-				c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
-				return
-			}
-			log.Error().Str("handler", "get status").Str("requestID", id.String()).Err(err).Msg("found request error")
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status"})
+			return storage.TextRequest{}, err
+		}
+		return *request, nil
+	})
+	if err != nil {
+		if err == storage.ErrNotFound {
+			log.Error().Str("handler", "get status").Str("requestID", id.String()).Err(err).Msg("request not found")
+			c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
 			return
 		}
-		cache.SetInRedis(result, r.App.Redis, id)
+		log.Error().Str("handler", "get status").Str("requestID", id.String()).Err(err).Msg("found request error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status"})
+		return
 	}
+	result := &cached
+	if !canAccess(c, result) {
+		log.Error().Str("handler", "get status").Str("requestID", id.String()).Msg("request of another tenant")
+		c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+		return
+	}
+
+	if waiter != nil && !result.Status.IsTerminal() {
+		result = r.waitForStatus(c, waiter, result, wait)
+	}
+
+	writeStatus(c, http.StatusOK, result)
+}
 
-	// success: full result, else only status
+// writeStatus write full result on success, else only status
+func writeStatus(c *gin.Context, code int, result *storage.TextRequest) {
 	switch result.Status {
 	case storage.InProcess, storage.Failed:
-		c.JSON(http.StatusOK, JsonStatusOnlyOutput{ID: result.ID, Text: result.Text, Status: result.Status})
+		output := JsonStatusOnlyOutput{
+			ID:       result.ID,
+			Text:     result.Text,
+			BlobID:   result.Blob,
+			Size:     result.Size,
+			Filename: result.Filename,
+			Format:   result.Format,
+			Encoding: result.Encoding,
+			Status:   result.Status,
+		}
+		if result.Status == storage.InProcess {
+			output.Progress = result.Progress
+		}
+		c.JSON(code, output)
 	case storage.Success:
-		c.JSON(http.StatusOK, JsonRequest{
-			ID:     result.ID,
-			Text:   result.Text,
-			Status: result.Status,
-			Analyze: JsonAnalyze{
-				WordCount:         result.Analyze.WordCount,
-				CharCount:         result.Analyze.CharCount,
-				SentenceCount:     result.Analyze.SentenceCount,
-				AverageWordLength: result.Analyze.AverageWordLength,
-			},
-		})
+		output := JsonRequest{
+			ID:              result.ID,
+			Text:            result.Text,
+			BlobID:          result.Blob,
+			Size:            result.Size,
+			Filename:        result.Filename,
+			Format:          result.Format,
+			Encoding:        result.Encoding,
+			Status:          result.Status,
+			Analyze:         newJsonAnalyze(result.Analyze),
+			AnalyzerVersion: result.Analyze.AnalyzerVersion,
+		}
+		if result.DuplicateOf != uuid.Nil {
+			output.DuplicateOf = &result.DuplicateOf
+		}
+		c.JSON(code, output)
 	}
 }
 
@@ -153,8 +296,14 @@ func (r *Routes) healthCheck(c *gin.Context) {
 // @Produce json
 // @Param payload body JsonRequest true "Updated request details including analysis results"
 // @Success 200 {object} StatusResponse
+// @Param X-Internal-Timestamp header string true "Unix time of signing"
+// @Param X-Internal-Nonce header string true "Unique value of the callback"
+// @Param X-Internal-Signature header string true "sha256=HMAC-SHA256(INTERNAL_SECRET, timestamp.nonce.body)"
 // @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 410 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /result [put]
 func (r *Routes) updateAnalyze(c *gin.Context) {
@@ -162,7 +311,6 @@ func (r *Routes) updateAnalyze(c *gin.Context) {
 	defer func() {
 		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "updateAnalyze")
 	}()
-	//TODO receive only service ip
 	var answer JsonRequest
 	if err := c.ShouldBindJSON(&answer); err != nil {
 		log.Error().Str("handler", "update analyze").Err(err).Msg("Invalid request body")
@@ -170,24 +318,112 @@ func (r *Routes) updateAnalyze(c *gin.Context) {
 		return
 	}
 
-	analyzeResult := storage.AnalyzeResult{
-		WordCount:         answer.Analyze.WordCount,
-		CharCount:         answer.Analyze.CharCount,
-		SentenceCount:     answer.Analyze.SentenceCount,
-		AverageWordLength: answer.Analyze.AverageWordLength,
-	}
-	result, err := r.App.Store.Requests.UpdateRequest(answer.ID, answer.Status, analyzeResult)
-	if err != nil {
+	analyzeResult := answer.Analyze.analyzeResult(answer.AnalyzerVersion)
+	if _, err := r.updateStatus(answer.ID, answer.Status, analyzeResult); err != nil {
 		if err == storage.ErrNotFound {
 			log.Error().Str("handler", "update analyze").Str("requestID", answer.ID.String()).Err(err).Msg("request not found")
 			c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
 			return
 		}
+		if err == storage.ErrAnonymized {
+			log.Error().Str("handler", "update analyze").Str("requestID", answer.ID.String()).Err(err).Msg("result of anonymized request refused")
+			c.JSON(http.StatusGone, gin.H{"error": "request is anonymized"})
+			return
+		}
+		if err == storage.ErrStaleUpdate {
+			log.Error().Str("handler", "update analyze").Str("requestID", answer.ID.String()).Err(err).Msg("stale update ignored")
+			c.JSON(http.StatusConflict, gin.H{"error": "request is already completed"})
+			return
+		}
 		log.Error().Str("handler", "update analyze").Str("requestID", answer.ID.String()).Err(err).Msg("request update error")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "request update error"})
 		return
 	}
-	cache.SetInRedis(result, r.App.Redis, result.ID)
 
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
+
+// @Summary Update analysis progress of a document
+// @Description Saves the percentage of analyzed document reported by the analyzer service, shown in status while the request is in process.
+// @Tags Microservices
+// @Accept json
+// @Produce json
+// @Param payload body JsonProgressInput true "Request ID and progress percentage"
+// @Param X-Internal-Timestamp header string true "Unix time of signing"
+// @Param X-Internal-Nonce header string true "Unique value of the callback"
+// @Param X-Internal-Signature header string true "sha256=HMAC-SHA256(INTERNAL_SECRET, timestamp.nonce.body)"
+// @Success 200 {object} StatusResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /progress [post]
+func (r *Routes) updateProgress(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "updateProgress")
+	}()
+	var input JsonProgressInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error().Str("handler", "update progress").Err(err).Msg("Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	result, err := r.App.Store.Requests.UpdateProgress(input.ID, input.Progress)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			log.Error().Str("handler", "update progress").Str("requestID", input.ID.String()).Err(err).Msg("request not found")
+			c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+			return
+		}
+		if err == storage.ErrStaleUpdate {
+			c.JSON(http.StatusConflict, gin.H{"error": "request is already completed"})
+			return
+		}
+		log.Error().Str("handler", "update progress").Str("requestID", input.ID.String()).Err(err).Msg("request update error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "request update error"})
+		return
+	}
+	r.App.Cache.Set(context.Background(), result.ID.String(), *result)
+	r.App.Events.Publish(events.NewStatusEvent(result))
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// updateStatus save status and analyze of request, write it through to cache and notify subscribers
+//
+// if store update fails, cached copy is dropped, so readers load the request from store
+func (r *Routes) updateStatus(id uuid.UUID, status storage.Status, analyze storage.AnalyzeResult) (*storage.TextRequest, error) {
+	if status == storage.Success {
+		// request is indexed after update, so only requests analyzed before it are similar
+		if request, err := r.App.Store.Requests.GetRequest(id); err == nil {
+			analyze.Similar = r.similarTo(analyze.Signature, request.Tenant, id)
+		}
+	}
+	result, err := r.App.Store.Requests.UpdateRequest(id, status, analyze)
+	if err != nil {
+		if err != storage.ErrStaleUpdate {
+			if err := r.App.Cache.Delete(context.Background(), id.String()); err != nil {
+				log.Error().Str("event", "update status").Str("requestID", id.String()).Err(err).Msg("failed to delete from cache")
+			}
+		}
+		return nil, err
+	}
+	r.App.Cache.Set(context.Background(), result.ID.String(), *result)
+	if result.Status == storage.Success {
+		r.App.Similar.Add(result.ID, result.Tenant, result.Analyze.Signature)
+		r.App.Search.Enqueue(*result)
+		// request erased or anonymized after update, its removal from indexes may have run before they were added
+		if current, err := r.App.Store.Requests.GetRequest(id); err == storage.ErrNotFound || err == nil && current.Anonymized() {
+			r.App.Similar.Remove(id)
+			r.App.Search.Remove(id)
+		}
+	}
+	r.App.Events.Publish(events.NewStatusEvent(result))
+	if result.Status.IsTerminal() {
+		r.App.Webhooks.Enqueue(*result)
+	}
+	return result, nil
+}
@@ -0,0 +1,102 @@
+package routes
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"receiver/cache"
+	"receiver/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// fingerprint return sha256 of request body
+func fingerprint(req JsonTextInput) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// reserveIdempotencyKey reserve Idempotency-Key header for request
+//
+// return reserved key (empty if header is not set) and false,
+// or true if response is already written: replay of original request or an error
+func (r *Routes) reserveIdempotencyKey(c *gin.Context, req JsonTextInput) (string, bool) {
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if key == "" {
+		return "", false
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+		return "", true
+	}
+
+	// keys of different tenants never collide
+	if tenant := tenantOf(c); tenant != "" {
+		key = tenant + ":" + key
+	}
+
+	requestPrint := fingerprint(req)
+	record, reserved, err := cache.ReserveIdempotencyKey(r.App.Redis, key, requestPrint, r.App.Config.IdempotencyTTL)
+	if err != nil {
+		// same as other cache errors: serve request without the guarantee
+		log.Error().Str("handler", "handle request").Str("idempotencyKey", key).Err(err).Msg("idempotency store unavailable, key is ignored")
+		return "", false
+	}
+	if reserved {
+		return key, false
+	}
+
+	if record.Fingerprint != requestPrint {
+		c.JSON(http.StatusConflict, gin.H{"error": "Idempotency-Key is already used with another request body"})
+		return "", true
+	}
+	if record.RequestID == uuid.Nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "request with this Idempotency-Key is in progress"})
+		return "", true
+	}
+
+	request, err := r.App.Store.Requests.GetRequest(record.RequestID)
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+			return "", true
+		}
+		log.Error().Str("handler", "handle request").Str("requestID", record.RequestID.String()).Err(err).Msg("found request error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status"})
+		return "", true
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.JSON(http.StatusOK, IdResponse{ID: request.ID.String(), Status: request.Status})
+	return "", true
+}
+
+// completeIdempotencyKey bind reserved key to created request
+func (r *Routes) completeIdempotencyKey(key string, req JsonTextInput, id uuid.UUID) {
+	if key == "" {
+		return
+	}
+	record := cache.IdempotencyRecord{Fingerprint: fingerprint(req), RequestID: id}
+	if err := cache.CompleteIdempotencyKey(r.App.Redis, key, record, r.App.Config.IdempotencyTTL); err != nil {
+		log.Error().Str("handler", "handle request").Str("idempotencyKey", key).Err(err).Msg("Failed to complete idempotency key")
+	}
+}
+
+// releaseIdempotencyKey free reserved key after failed request, so client may retry
+func (r *Routes) releaseIdempotencyKey(key string) {
+	if key == "" {
+		return
+	}
+	if err := cache.ReleaseIdempotencyKey(r.App.Redis, key); err != nil {
+		log.Error().Str("handler", "handle request").Str("idempotencyKey", key).Err(err).Msg("Failed to release idempotency key")
+	}
+}
@@ -6,34 +6,201 @@ import (
 	"fmt"
 	"net/http"
 	"receiver/internal/config"
+	"receiver/internal/storage"
+	"sync"
+	"time"
 
-	"github.com/google/uuid"
+	"github.com/Critma/textAnalyzer/shared/limits"
+	"github.com/Critma/textAnalyzer/shared/signature"
+	"golang.org/x/sync/singleflight"
 )
 
-func sendToAnalyzer(app config.Application, id uuid.UUID, text string) error {
-	var toSend *JsonToAnalyzer = &JsonToAnalyzer{ID: id.String(), Text: text}
+const (
+	// analyzerVersionTTL is how long analyzer version is cached
+	analyzerVersionTTL = time.Minute
+	// analyzerVersionErrorTTL is how long failure to get analyzer version is cached
+	analyzerVersionErrorTTL = 5 * time.Second
+)
+
+var analyzerVersion struct {
+	sync.Mutex
+	value   string
+	err     error
+	expires time.Time
+	// group share one fetch between concurrent callers, the lock is not held during it
+	group singleflight.Group
+}
+
+// sendToAnalyzer send text of request, or reference to its blob, to analyzer service
+func sendToAnalyzer(app config.Application, request *storage.TextRequest) error {
+	var toSend *JsonToAnalyzer = &JsonToAnalyzer{ID: request.ID.String(), Text: request.Text, BlobID: request.Blob, Normalize: request.Normalize}
 	data, err := json.Marshal(toSend)
 	if err != nil {
 		return err
 	}
+	resp, err := postAnalyzer(app, "/api/v1/analyze", data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("analyzer service returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// sendCompareToAnalyzer send texts of comparison to analyzer service,
+// limit is returned if analyzer rejected texts as too long to compare
+func sendCompareToAnalyzer(app config.Application, comparison *storage.Comparison, left, right string) (*limits.JsonLimitError, error) {
+	data, err := json.Marshal(JsonCompareToAnalyzer{ID: comparison.ID.String(), Left: left, Right: right})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := postAnalyzer(app, "/api/v1/compare", data)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		var limit limits.JsonLimitError
+		if err := json.NewDecoder(resp.Body).Decode(&limit); err != nil {
+			return nil, err
+		}
+		return &limit, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("analyzer service returned status %d", resp.StatusCode)
+	}
+	return nil, nil
+}
+
+// diffFromAnalyzer compute diff of two versions of text by analyzer service,
+// limit is returned if analyzer rejected texts as too long to diff
+func diffFromAnalyzer(app config.Application, input JsonDiffToAnalyzer) (*JsonDiff, *limits.JsonLimitError, error) {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := postAnalyzer(app, "/api/v1/diff", data)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusRequestEntityTooLarge {
+		var limit limits.JsonLimitError
+		if err := json.NewDecoder(resp.Body).Decode(&limit); err != nil {
+			return nil, nil, err
+		}
+		return nil, &limit, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("analyzer service returned status %d", resp.StatusCode)
+	}
+	var diff JsonDiff
+	if err := json.NewDecoder(resp.Body).Decode(&diff); err != nil {
+		return nil, nil, err
+	}
+	return &diff, nil, nil
+}
+
+// kwicFromAnalyzer find occurrences of term in text by analyzer service,
+// rejection is returned if analyzer rejected the query
+func kwicFromAnalyzer(app config.Application, query JsonKwicToAnalyzer) (*JsonKwicList, *ErrorResponse, error) {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := postAnalyzer(app, "/api/v1/kwic", data)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusBadRequest {
+		var rejection ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&rejection); err != nil {
+			return nil, nil, err
+		}
+		return nil, &rejection, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("analyzer service returned status %d", resp.StatusCode)
+	}
+	var list JsonKwicList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		return nil, nil, err
+	}
+	return &list, nil, nil
+}
 
-	analyzerUrl := fmt.Sprintf("http://%s/api/v1/analyze", app.Config.AnalyzerAddr)
+// postAnalyzer post json data to path of analyzer service, signed with INTERNAL_SECRET,
+// caller must close response body
+func postAnalyzer(app config.Application, path string, data []byte) (*http.Response, error) {
+	analyzerUrl := fmt.Sprintf("http://%s%s", app.Config.AnalyzerAddr, path)
 	req, err := http.NewRequest("POST", analyzerUrl, bytes.NewBuffer(data))
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if err := signature.SignRequest(req, app.Config.Internal.Secret, data); err != nil {
+		return nil, err
+	}
+	return app.HttpClient.Do(req)
+}
+
+// getAnalyzerVersion return version of analyzer logic, cached for analyzerVersionTTL,
+// failures are cached for analyzerVersionErrorTTL
+func getAnalyzerVersion(app config.Application) (string, error) {
+	analyzerVersion.Lock()
+	if time.Now().Before(analyzerVersion.expires) {
+		defer analyzerVersion.Unlock()
+		return analyzerVersion.value, analyzerVersion.err
+	}
+	analyzerVersion.Unlock()
+
+	version, err, _ := analyzerVersion.group.Do("version", func() (any, error) {
+		version, err := fetchAnalyzerVersion(app)
+		analyzerVersion.Lock()
+		defer analyzerVersion.Unlock()
+		analyzerVersion.value, analyzerVersion.err = version, err
+		if err != nil {
+			analyzerVersion.expires = time.Now().Add(analyzerVersionErrorTTL)
+		} else {
+			analyzerVersion.expires = time.Now().Add(analyzerVersionTTL)
+		}
+		return version, err
+	})
+	return version.(string), err
+}
 
-	// send to analyzer service
+// fetchAnalyzerVersion get version of analyzer logic from analyzer service
+func fetchAnalyzerVersion(app config.Application) (string, error) {
+	analyzerUrl := fmt.Sprintf("http://%s/api/v1/version", app.Config.AnalyzerAddr)
+	req, err := http.NewRequest("GET", analyzerUrl, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := signature.SignRequest(req, app.Config.Internal.Secret, nil); err != nil {
+		return "", err
+	}
 	resp, err := app.HttpClient.Do(req)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("analyzer service returned status %d", resp.StatusCode)
+		return "", fmt.Errorf("analyzer service returned status %d", resp.StatusCode)
 	}
-
-	return nil
+	var version struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&version); err != nil {
+		return "", err
+	}
+	return version.Version, nil
 }
@@ -0,0 +1,92 @@
+package routes
+
+import (
+	"context"
+	"net/http"
+	"receiver/internal/auth"
+	"receiver/internal/metrics"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// @Summary Create API key
+// @Description Issues an API key of a tenant. The key is returned only in this response, the service stores its SHA-256 hash. Requires Authorization: Bearer ADMIN_TOKEN.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param payload body JsonKeyInput true "Key tenant, name and daily quota of characters"
+// @Success 201 {object} JsonAPIKey
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/keys [post]
+func (r *Routes) createKey(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "createKey")
+	}()
+	var input JsonKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		log.Error().Str("handler", "create key").Err(err).Msg("Invalid request body")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	plain, key, err := r.App.Keys.Create(context.Background(), input.Tenant, input.Name, input.DailyQuota)
+	if err != nil {
+		if err == auth.ErrEmptyTenant {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tenant cannot be empty"})
+			return
+		}
+		log.Error().Str("handler", "create key").Err(err).Msg("create key error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create key"})
+		return
+	}
+	log.Info().Str("event", "create key").Str("keyID", key.ID.String()).Str("tenant", key.Tenant).Msg("api key created")
+	c.JSON(http.StatusCreated, JsonAPIKey{
+		ID:         key.ID,
+		Key:        plain,
+		Tenant:     key.Tenant,
+		Name:       key.Name,
+		DailyQuota: key.DailyQuota,
+		CreatedAt:  key.CreatedAt,
+	})
+}
+
+// @Summary Revoke API key
+// @Description Deletes an API key, requests with it are rejected at once. Requires Authorization: Bearer ADMIN_TOKEN.
+// @Tags Admin
+// @Produce json
+// @Param id path string true "Key ID"
+// @Success 200 {object} StatusResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/keys/{id} [delete]
+func (r *Routes) revokeKey(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "revokeKey")
+	}()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Error().Str("handler", "revoke key").Err(err).Msg("Invalid ID")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	if err := r.App.Keys.Revoke(context.Background(), id); err != nil {
+		if err == auth.ErrKeyNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+		log.Error().Str("handler", "revoke key").Str("keyID", id.String()).Err(err).Msg("revoke key error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke key"})
+		return
+	}
+	log.Info().Str("event", "revoke key").Str("keyID", id.String()).Msg("api key revoked")
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
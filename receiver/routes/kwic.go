@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"net/http"
+	"receiver/internal/metrics"
+	"receiver/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultKwicWindow = 5
+	maxKwicWindow     = 50
+)
+
+// @Summary List occurrences of a term in request text
+// @Description Returns a page of keyword-in-context lines: every occurrence of the term in the stored text with words around it, computed by analyzer. Left contexts are padded with spaces, so keywords are aligned in monospace font.
+// @Tags Requests
+// @Produce json
+// @Param id path string true "Unique Request ID"
+// @Param term query string true "Word to find"
+// @Param window query int false "Words of context on each side, 1..50 (default 5)"
+// @Param stems query bool false "Match words with the same stem"
+// @Param limit query int false "Page size, 1..100 (default 20)"
+// @Param offset query int false "Number of occurrences to skip"
+// @Success 200 {object} JsonKwicList
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /requests/{id}/kwic [get]
+func (r *Routes) listKwic(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "listKwic")
+	}()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Error().Str("handler", "list kwic").Err(err).Msg("Invalid ID")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	query := JsonKwicToAnalyzer{Term: c.Query("term"), Window: defaultKwicWindow, Limit: defaultPageSize}
+	if query.Term == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "term is required"})
+		return
+	}
+	if param := c.Query("window"); param != "" {
+		query.Window, err = strconv.Atoi(param)
+		if err != nil || query.Window < 1 || query.Window > maxKwicWindow {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "window must be 1.." + strconv.Itoa(maxKwicWindow)})
+			return
+		}
+	}
+	if param := c.Query("stems"); param != "" {
+		query.Stems, err = strconv.ParseBool(param)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "stems must be true or false"})
+			return
+		}
+	}
+	if param := c.Query("limit"); param != "" {
+		query.Limit, err = strconv.Atoi(param)
+		if err != nil || query.Limit < 1 || query.Limit > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+	}
+	if param := c.Query("offset"); param != "" {
+		query.Offset, err = strconv.Atoi(param)
+		if err != nil || query.Offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative number"})
+			return
+		}
+	}
+
+	request, err := r.App.Store.Requests.GetRequest(id)
+	if err == nil && !canAccess(c, request) {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+			return
+		}
+		log.Error().Str("handler", "list kwic").Str("requestID", id.String()).Err(err).Msg("found request error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list occurrences"})
+		return
+	}
+	if request.Text == "" && request.Blob == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Text of request is erased"})
+		return
+	}
+	query.Text, query.BlobID = request.Text, request.Blob
+
+	list, rejection, err := kwicFromAnalyzer(r.App, query)
+	if err != nil {
+		log.Error().Str("handler", "list kwic").Str("requestID", id.String()).Err(err).Msg("analyzer error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list occurrences"})
+		return
+	}
+	if rejection != nil {
+		c.JSON(http.StatusBadRequest, rejection)
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
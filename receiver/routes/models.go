@@ -2,15 +2,28 @@ package routes
 
 import (
 	"receiver/internal/storage"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type JsonRequest struct {
-	ID      uuid.UUID      `json:"id"`
-	Text    string         `json:"text,omitempty"`
-	Status  storage.Status `json:"status"`
-	Analyze JsonAnalyze    `json:"analyze,omitempty"`
+	ID   uuid.UUID `json:"id"`
+	Text string    `json:"text,omitempty"`
+	// BlobID reference content of large document instead of Text
+	BlobID string `json:"blobId,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	// Filename and Format of uploaded file
+	Filename string `json:"filename,omitempty"`
+	Format   string `json:"format,omitempty"`
+	// Encoding of uploaded text before it was transcoded to UTF-8
+	Encoding string         `json:"encoding,omitempty"`
+	Status   storage.Status `json:"status"`
+	Analyze  JsonAnalyze    `json:"analyze,omitempty"`
+	// AnalyzerVersion is set by analyzer service
+	AnalyzerVersion string `json:"analyzerVersion,omitempty"`
+	// DuplicateOf is the request whose analyze of the same text was reused
+	DuplicateOf *uuid.UUID `json:"duplicateOf,omitempty"`
 }
 
 type JsonAnalyze struct {
@@ -18,29 +31,374 @@ type JsonAnalyze struct {
 	CharCount         int     `json:"charCount,omitempty"`
 	SentenceCount     int     `json:"sentenceCount,omitempty"`
 	AverageWordLength float64 `json:"averageWordLength,omitempty"`
+	UniqueWordCount   int     `json:"uniqueWordCount,omitempty"`
+	// TopWords are the most frequent lower-cased words
+	TopWords []JsonWordFrequency `json:"topWords,omitempty"`
+	// Normalization steps applied by analyzer before analysis
+	Normalization []string `json:"normalization,omitempty"`
+	// Similar are the most similar requests analyzed before this one
+	Similar []JsonSimilarRequest `json:"similar,omitempty"`
+	// Signature is sent by analyzer and is not shown to clients
+	Signature *JsonSignature `json:"signature,omitempty"`
+}
+
+// JsonSignature is a fingerprint of text computed by analyzer
+type JsonSignature struct {
+	MinHash []uint32 `json:"minHash"`
+	SimHash uint64   `json:"simHash"`
+}
+
+type JsonSimilarRequest struct {
+	ID uuid.UUID `json:"id"`
+	// Score is an estimated Jaccard similarity of 3-word shingles
+	Score float64 `json:"score"`
+	// SimHash is a share of equal bits of SimHash of words
+	SimHash float64 `json:"simHash"`
+}
+
+type JsonSimilarList struct {
+	Items []JsonSimilarRequest `json:"items"`
+}
+
+type JsonSearchResult struct {
+	// Total is a number of matching requests
+	Total int             `json:"total"`
+	Items []JsonSearchHit `json:"items"`
+}
+
+// JsonSearchHit is a matching request, Snippet is html escaped text fragment with matched words in <mark>
+type JsonSearchHit struct {
+	ID      uuid.UUID `json:"id"`
+	Score   float64   `json:"score"`
+	Snippet string    `json:"snippet"`
+}
+
+type JsonWordFrequency struct {
+	Word  string `json:"word"`
+	Count int    `json:"count"`
+}
+
+func newJsonAnalyze(analyze storage.AnalyzeResult) JsonAnalyze {
+	output := JsonAnalyze{
+		WordCount:         analyze.WordCount,
+		CharCount:         analyze.CharCount,
+		SentenceCount:     analyze.SentenceCount,
+		AverageWordLength: analyze.AverageWordLength,
+		UniqueWordCount:   analyze.UniqueWordCount,
+		Normalization:     analyze.Normalization,
+	}
+	for _, word := range analyze.TopWords {
+		output.TopWords = append(output.TopWords, JsonWordFrequency{Word: word.Word, Count: word.Count})
+	}
+	for _, similar := range analyze.Similar {
+		output.Similar = append(output.Similar, JsonSimilarRequest(similar))
+	}
+	return output
+}
+
+// analyzeResult return storage result of analyzer answer
+func (a JsonAnalyze) analyzeResult(analyzerVersion string) storage.AnalyzeResult {
+	result := storage.AnalyzeResult{
+		WordCount:         a.WordCount,
+		CharCount:         a.CharCount,
+		SentenceCount:     a.SentenceCount,
+		AverageWordLength: a.AverageWordLength,
+		UniqueWordCount:   a.UniqueWordCount,
+		Normalization:     a.Normalization,
+		AnalyzerVersion:   analyzerVersion,
+	}
+	for _, word := range a.TopWords {
+		result.TopWords = append(result.TopWords, storage.WordFrequency{Word: word.Word, Count: word.Count})
+	}
+	if a.Signature != nil {
+		result.Signature = storage.Signature(*a.Signature)
+	}
+	return result
 }
 
 type JsonStatusOnlyOutput struct {
-	ID     uuid.UUID      `json:"id"`
-	Text   string         `json:"text"`
-	Status storage.Status `json:"status"`
+	ID       uuid.UUID      `json:"id"`
+	Text     string         `json:"text"`
+	BlobID   string         `json:"blobId,omitempty"`
+	Size     int64          `json:"size,omitempty"`
+	Filename string         `json:"filename,omitempty"`
+	Format   string         `json:"format,omitempty"`
+	Encoding string         `json:"encoding,omitempty"`
+	Status   storage.Status `json:"status"`
+	// Progress is a percentage of analyzed document while request is in process
+	Progress int `json:"progress,omitempty"`
 }
 
 type JsonTextInput struct {
-	Text string `json:"text"`
+	Text     string   `json:"text"`
+	Language string   `json:"language,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	// CallbackURL is notified with the result when analysis completes
+	CallbackURL string `json:"callbackUrl,omitempty"`
+	// Normalize are normalization steps applied before analysis:
+	// entities, invisible, nfc or nfkc, yo, quotes, whitespace
+	Normalize []string `json:"normalize,omitempty"`
+}
+
+type JsonRequestSummary struct {
+	ID          uuid.UUID      `json:"id"`
+	Status      storage.Status `json:"status"`
+	Language    string         `json:"language,omitempty"`
+	Tags        []string       `json:"tags,omitempty"`
+	Filename    string         `json:"filename,omitempty"`
+	Format      string         `json:"format,omitempty"`
+	Encoding    string         `json:"encoding,omitempty"`
+	Analyze     JsonAnalyze    `json:"analyze,omitzero"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+	CompletedAt time.Time      `json:"completedAt,omitzero"`
+}
+
+type JsonRequestList struct {
+	Items []JsonRequestSummary `json:"items"`
+	// NextCursor is empty on the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// JsonProgressInput is a progress of document analysis reported by analyzer
+type JsonProgressInput struct {
+	ID       uuid.UUID `json:"id" binding:"required"`
+	Progress int       `json:"progress" binding:"min=0,max=100"`
 }
 
 type JsonToAnalyzer struct {
 	ID   string `json:"id"`
-	Text string `json:"text"`
+	Text string `json:"text,omitempty"`
+	// BlobID is set instead of Text for large documents, analyzer reads it from shared blob storage
+	BlobID    string   `json:"blobId,omitempty"`
+	Normalize []string `json:"normalize,omitempty"`
+}
+
+// JsonCompareInput is a pair of texts or of request ids to compare or diff
+type JsonCompareInput struct {
+	Texts []string    `json:"texts,omitempty"`
+	IDs   []uuid.UUID `json:"ids,omitempty"`
+}
+
+type JsonComparison struct {
+	ID     uuid.UUID      `json:"id"`
+	Status storage.Status `json:"status"`
+	// Requests whose texts are compared, empty if texts were submitted directly
+	Requests []uuid.UUID           `json:"requests,omitempty"`
+	Result   *JsonComparisonResult `json:"result,omitempty"`
+	// Redacted is set if texts of compared requests were erased, passages are not shown then
+	Redacted bool `json:"redacted,omitempty"`
+	// AnalyzerVersion is set by analyzer service
+	AnalyzerVersion string    `json:"analyzerVersion,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	CompletedAt     time.Time `json:"completedAt,omitzero"`
+}
+
+// JsonComparisonResult is a similarity of two texts, computed on lower-cased words without punctuation
+type JsonComparisonResult struct {
+	// Jaccard similarity of 3-word shingles
+	Jaccard float64 `json:"jaccard"`
+	// Cosine similarity of TF-IDF vectors
+	Cosine float64 `json:"cosine"`
+	// EditDistance is a word level edit distance divided by length of the longer text, 0 for equal texts
+	EditDistance float64 `json:"editDistance"`
+	// Passages are the longest common word sequences, longest first
+	Passages []JsonPassage `json:"passages,omitempty"`
+}
+
+type JsonPassage struct {
+	// Text of passage as it is written in the first text
+	Text  string `json:"text"`
+	Words int    `json:"words"`
+	// LeftOffset and RightOffset are offsets of passage in chars of the first and the second text
+	LeftOffset  int `json:"leftOffset"`
+	RightOffset int `json:"rightOffset"`
+}
+
+func newJsonComparison(comparison *storage.Comparison) JsonComparison {
+	output := JsonComparison{
+		ID:          comparison.ID,
+		Status:      comparison.Status,
+		Redacted:    comparison.Redacted,
+		CreatedAt:   comparison.CreatedAt,
+		CompletedAt: comparison.CompletedAt,
+	}
+	if comparison.Requests[0] != uuid.Nil {
+		output.Requests = comparison.Requests[:]
+	}
+	if comparison.Status == storage.Success {
+		result := comparison.Result
+		output.Result = &JsonComparisonResult{
+			Jaccard:      result.Jaccard,
+			Cosine:       result.Cosine,
+			EditDistance: result.EditDistance,
+		}
+		for _, passage := range result.Passages {
+			output.Result.Passages = append(output.Result.Passages, JsonPassage(passage))
+		}
+		output.AnalyzerVersion = result.AnalyzerVersion
+	}
+	return output
+}
+
+// comparisonResult return storage result of analyzer answer
+func (r JsonComparisonResult) comparisonResult(analyzerVersion string) storage.ComparisonResult {
+	result := storage.ComparisonResult{
+		Jaccard:         r.Jaccard,
+		Cosine:          r.Cosine,
+		EditDistance:    r.EditDistance,
+		AnalyzerVersion: analyzerVersion,
+	}
+	for _, passage := range r.Passages {
+		result.Passages = append(result.Passages, storage.Passage(passage))
+	}
+	return result
+}
+
+type JsonCompareToAnalyzer struct {
+	ID    string `json:"id"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+// JsonCompareAnswer is a comparison result sent by analyzer
+type JsonCompareAnswer struct {
+	ID              uuid.UUID            `json:"id" binding:"required"`
+	Status          storage.Status       `json:"status"`
+	Compare         JsonComparisonResult `json:"compare"`
+	AnalyzerVersion string               `json:"analyzerVersion"`
+}
+
+type JsonAuditRecord struct {
+	ID        uuid.UUID           `json:"id"`
+	RequestID uuid.UUID           `json:"requestId"`
+	Action    storage.AuditAction `json:"action"`
+	Reason    string              `json:"reason"`
+	At        time.Time           `json:"at"`
+}
+
+type JsonWebhookDelivery struct {
+	ID         uuid.UUID `json:"id"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Success    bool      `json:"success"`
+	At         time.Time `json:"at"`
+	DurationMs int64     `json:"durationMs"`
+}
+
+// JsonDiffToAnalyzer is a pair of versions of text, Left is the old one and Right is the new one,
+// metrics of versions taken from requests are computed with normalization of the requests
+type JsonDiffToAnalyzer struct {
+	Left           string   `json:"left"`
+	Right          string   `json:"right"`
+	LeftNormalize  []string `json:"leftNormalize,omitempty"`
+	RightNormalize []string `json:"rightNormalize,omitempty"`
+}
+
+// JsonDiff is a difference of two versions of text, computed by analyzer
+type JsonDiff struct {
+	// Words are changes of words, compared as written without surrounding punctuation
+	Words []JsonDiffOp `json:"words"`
+	// Sentences are changes of sentences, compared with whitespace collapsed
+	Sentences []JsonDiffOp    `json:"sentences"`
+	Metrics   JsonMetricsDiff `json:"metrics"`
+}
+
+// JsonDiffOp change Left part of the first text to Right part of the second one,
+// insert has empty Left and delete has empty Right
+type JsonDiffOp struct {
+	// Op is insert, delete or replace
+	Op    string `json:"op"`
+	Left  string `json:"left"`
+	Right string `json:"right"`
+	// LeftOffset and RightOffset are offsets of change in chars
+	LeftOffset  int `json:"leftOffset"`
+	RightOffset int `json:"rightOffset"`
+}
+
+// JsonMetricsDiff is every analyze metric of both versions and its change
+type JsonMetricsDiff struct {
+	WordCount         JsonIntDelta   `json:"wordCount"`
+	CharCount         JsonIntDelta   `json:"charCount"`
+	SentenceCount     JsonIntDelta   `json:"sentenceCount"`
+	AverageWordLength JsonFloatDelta `json:"averageWordLength"`
+	UniqueWordCount   JsonIntDelta   `json:"uniqueWordCount"`
+	// TopWords are counts of top words of either version, the largest changes first
+	TopWords []JsonWordDelta `json:"topWords"`
+}
+
+type JsonIntDelta struct {
+	Left  int `json:"left"`
+	Right int `json:"right"`
+	Delta int `json:"delta"`
+}
+
+type JsonFloatDelta struct {
+	Left  float64 `json:"left"`
+	Right float64 `json:"right"`
+	Delta float64 `json:"delta"`
+}
+
+type JsonWordDelta struct {
+	Word  string `json:"word"`
+	Left  int    `json:"left"`
+	Right int    `json:"right"`
+	Delta int    `json:"delta"`
+}
+
+// JsonKwicToAnalyzer is a concordance query over text of request, document is referenced by blob id
+type JsonKwicToAnalyzer struct {
+	Text   string `json:"text"`
+	BlobID string `json:"blobId,omitempty"`
+	Term   string `json:"term"`
+	Stems  bool   `json:"stems,omitempty"`
+	Window int    `json:"window"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+type JsonKwicList struct {
+	// Total is a number of occurrences of term in text
+	Total int            `json:"total"`
+	Items []JsonKwicLine `json:"items"`
+}
+
+// JsonKwicLine is an occurrence of term with its context,
+// Left is padded with spaces so keywords of the page are aligned
+type JsonKwicLine struct {
+	Left    string `json:"left"`
+	Keyword string `json:"keyword"`
+	Right   string `json:"right"`
+	// Offset of keyword in text in chars
+	Offset int `json:"offset"`
 }
 
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+type JsonKeyInput struct {
+	Tenant string `json:"tenant" binding:"required"`
+	Name   string `json:"name"`
+	// DailyQuota of submitted characters, 0 is unlimited
+	DailyQuota int `json:"dailyQuota" binding:"min=0"`
+}
+
+type JsonAPIKey struct {
+	ID uuid.UUID `json:"id"`
+	// Key is shown only once, on creation
+	Key        string    `json:"key,omitempty"`
+	Tenant     string    `json:"tenant"`
+	Name       string    `json:"name"`
+	DailyQuota int       `json:"dailyQuota"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
 type IdResponse struct {
-	ID string `json:"id"`
+	ID     string         `json:"id"`
+	Status storage.Status `json:"status,omitempty"`
 }
 
 type StatusResponse struct {
@@ -0,0 +1,31 @@
+package routes
+
+import (
+	"strings"
+
+	"github.com/Critma/textAnalyzer/shared/normalize"
+)
+
+// parseNormalize return normalization steps in canonical order without repeats
+//
+// the same text with the same steps is analyzed once, so steps are part of dedup key
+func parseNormalize(names []string) ([]string, error) {
+	steps, err := normalize.Parse(names)
+	if err != nil {
+		return nil, err
+	}
+	return steps.Names(), nil
+}
+
+// splitNormalize return comma separated steps of query or form params
+func splitNormalize(params []string) []string {
+	var names []string
+	for _, param := range params {
+		for name := range strings.SplitSeq(param, ",") {
+			if strings.TrimSpace(name) != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"errors"
+	"receiver/internal/storage"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidStatus = errors.New("invalid status")
+
+// parseFilter read status, language, tag, from, to and cursor query params into storage.Query
+// scoped to caller tenant
+//
+// status and tag may be repeated or comma separated, from/to are RFC3339 timestamps
+func parseFilter(c *gin.Context) (storage.Query, error) {
+	query := storage.Query{Tenant: tenantOf(c)}
+	for _, param := range c.QueryArray("status") {
+		for s := range strings.SplitSeq(param, ",") {
+			status := storage.Status(strings.TrimSpace(s))
+			switch status {
+			case storage.InProcess, storage.Success, storage.Failed:
+				query.Statuses = append(query.Statuses, status)
+			default:
+				return query, errInvalidStatus
+			}
+		}
+	}
+
+	query.Language = normalizeLanguage(c.Query("language"))
+	for _, param := range c.QueryArray("tag") {
+		query.Tags = append(query.Tags, normalizeTags(strings.Split(param, ","))...)
+	}
+
+	var err error
+	if from := c.Query("from"); from != "" {
+		if query.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return query, err
+		}
+	}
+	if to := c.Query("to"); to != "" {
+		if query.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return query, err
+		}
+	}
+
+	query.After, err = storage.ParseCursor(c.Query("cursor"))
+	return query, err
+}
+
+// normalizeLanguage return lower-cased language code
+func normalizeLanguage(language string) string {
+	return strings.ToLower(strings.TrimSpace(language))
+}
+
+// normalizeTags trim tags and drop empty or repeated ones
+func normalizeTags(tags []string) []string {
+	var result []string
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
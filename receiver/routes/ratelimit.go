@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"github.com/Critma/textAnalyzer/shared/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// ipBuckets limit request before its api key is looked up: per client ip and globally
+//
+// ip bucket is the caller bucket if auth is disabled, else it limits requests with any (even invalid) key from one ip
+func (r *Routes) ipBuckets(c *gin.Context) []ratelimit.Bucket {
+	cfg := r.App.Config.RateLimit
+	ip := ratelimit.Bucket{Scope: "ip", Key: "ip:" + c.ClientIP(), Limit: cfg.IP}
+	if !r.App.Config.Auth.Enabled {
+		ip.Scope, ip.Limit = "client", cfg.Client
+	}
+	return []ratelimit.Bucket{ip, {Scope: "global", Key: "global", Limit: cfg.Global}}
+}
+
+// keyBuckets limit authenticated request per api key, none if auth is disabled
+func (r *Routes) keyBuckets(c *gin.Context) []ratelimit.Bucket {
+	key := apiKeyOf(c)
+	if key == nil {
+		return nil
+	}
+	return []ratelimit.Bucket{{Scope: "client", Key: "key:" + key.ID.String(), Limit: r.App.Config.RateLimit.Client}}
+}
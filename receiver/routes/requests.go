@@ -0,0 +1,95 @@
+package routes
+
+import (
+	"net/http"
+	"receiver/internal/metrics"
+	"receiver/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// @Summary List text analysis requests
+// @Description Returns a page of requests filtered by status, language, tags and creation time, sorted by creation time.
+// @Tags Requests
+// @Produce json
+// @Param status query string false "Comma separated statuses"
+// @Param language query string false "Request language"
+// @Param tag query string false "Comma separated tags, request must have all of them"
+// @Param from query string false "Created at or after (RFC3339)"
+// @Param to query string false "Created before (RFC3339)"
+// @Param order query string false "asc or desc (default) by creation time"
+// @Param limit query int false "Page size, 1..100 (default 20)"
+// @Param cursor query string false "nextCursor from the previous page"
+// @Success 200 {object} JsonRequestList
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /requests [get]
+func (r *Routes) listRequests(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "listRequests")
+	}()
+	query, err := parseFilter(c)
+	if err != nil {
+		log.Error().Str("handler", "list requests").Err(err).Msg("Invalid query")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: " + err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return
+	}
+
+	query.Limit = defaultPageSize
+	if limit := c.Query("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit < 1 || query.Limit > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+	}
+
+	// fetch one extra request to know if there is a next page
+	query.Limit++
+	requests, err := r.App.Store.Requests.ListRequests(query)
+	if err != nil {
+		log.Error().Str("handler", "list requests").Err(err).Msg("list requests error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list requests"})
+		return
+	}
+
+	response := JsonRequestList{Items: make([]JsonRequestSummary, 0, len(requests))}
+	if len(requests) == query.Limit {
+		requests = requests[:len(requests)-1]
+		response.NextCursor = storage.CursorOf(requests[len(requests)-1]).String()
+	}
+	for _, request := range requests {
+		response.Items = append(response.Items, JsonRequestSummary{
+			ID:          request.ID,
+			Status:      request.Status,
+			Language:    request.Language,
+			Tags:        request.Tags,
+			Filename:    request.Filename,
+			Format:      request.Format,
+			Encoding:    request.Encoding,
+			Analyze:     newJsonAnalyze(request.Analyze),
+			CreatedAt:   request.CreatedAt,
+			UpdatedAt:   request.UpdatedAt,
+			CompletedAt: request.CompletedAt,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
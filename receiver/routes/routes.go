@@ -2,10 +2,13 @@ package routes
 
 import (
 	"receiver/internal/config"
+	"receiver/internal/metrics"
 	"time"
 
 	_ "receiver/cmd/docs"
 
+	"github.com/Critma/textAnalyzer/shared/limits"
+	"github.com/Critma/textAnalyzer/shared/ratelimit"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
@@ -23,14 +26,20 @@ func New(app config.Application) Routes {
 	}
 }
 
+// exposeHeaders are readable by browser clients
+var exposeHeaders = []string{
+	"Content-Length", IdempotentReplayedHeader, QuotaRemainingHeader,
+	ratelimit.LimitHeader, ratelimit.RemainingHeader, ratelimit.ResetHeader, ratelimit.RetryAfterHeader,
+}
+
 // Mount connect routes(handlers) and middleware to group
 func (r *Routes) Mount(rg *gin.Engine) {
 	// cross config
 	rg.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://" + r.App.Config.Addr, "http://127.0.0.1:8080"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", IdempotencyKeyHeader},
+		ExposeHeaders:    exposeHeaders,
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -41,12 +50,66 @@ func (r *Routes) Mount(rg *gin.Engine) {
 	router := rg.Group("/api")
 	router = router.Group("/v1")
 	{
-		router.POST("/text", r.handleCreate)
-		router.GET("/status/:id", r.getStatus)
 		router.GET("/health", r.healthCheck)
 
-		router.POST("/result", r.updateAnalyze)
-
 		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
+	if r.App.Config.Internal.Addr == "" {
+		r.mountInternal(router)
+	}
+
+	sizes := r.App.Config.Limits
+	// rate limited per ip and globally before api key is checked, so requests with invalid keys are limited too,
+	// then per api key
+	limitIP := r.App.Limiter.Middleware(metrics.ObserveRateLimit, r.ipBuckets)
+	limitKey := r.App.Limiter.Middleware(metrics.ObserveRateLimit, r.keyBuckets)
+	// public api, requires api key if auth is enabled
+	api := router.Group("", limitIP, r.authenticate, limitKey, limits.Body(sizes.MaxBodyBytes))
+	{
+		api.POST("/text", r.handleCreate)
+		api.POST("/analyze/sync", r.handleSyncAnalyze)
+		api.POST("/compare", r.handleCompare)
+		api.GET("/compare/:id", r.getComparison)
+		api.POST("/diff", r.handleDiff)
+		api.DELETE("/text/:id", r.deleteRequest)
+		api.GET("/status/:id", r.getStatus)
+		api.GET("/status/:id/stream", r.streamStatus)
+		api.GET("/ws", r.streamWebsocket)
+		api.GET("/requests", r.listRequests)
+		api.GET("/requests/export", r.exportRequests)
+		api.GET("/requests/:id/deliveries", r.listDeliveries)
+		api.GET("/requests/:id/similar", r.listSimilar)
+		api.GET("/requests/:id/kwic", r.listKwic)
+		api.GET("/search", r.search)
+		api.GET("/audit", r.listAudit)
+	}
+	// large documents are streamed to blob storage, files are extracted to text
+	documents := router.Group("", limitIP, r.authenticate, limitKey, limits.Body(sizes.MaxDocumentBytes))
+	{
+		documents.POST("/documents", r.handleDocument)
+		documents.POST("/upload", r.handleUpload)
+	}
+
+	admin := router.Group("/admin", r.authenticateAdmin, limits.Body(sizes.MaxBodyBytes))
+	{
+		admin.POST("/keys", r.createKey)
+		admin.DELETE("/keys/:id", r.revokeKey)
+	}
+}
+
+// MountInternal connect service-to-service routes to internal listener
+func (r *Routes) MountInternal(rg *gin.Engine) {
+	router := rg.Group("/api")
+	router = router.Group("/v1")
+	router.GET("/health", r.healthCheck)
+	r.mountInternal(router)
+}
+
+func (r *Routes) mountInternal(router *gin.RouterGroup) {
+	internal := router.Group("", limits.Body(r.App.Config.Limits.MaxBodyBytes), r.App.Verifier.Middleware(r.App.Config.Limits.MaxBodyBytes))
+	{
+		internal.POST("/result", r.updateAnalyze)
+		internal.POST("/progress", r.updateProgress)
+		internal.POST("/compare/result", r.updateComparison)
+	}
 }
@@ -0,0 +1,64 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"receiver/internal/metrics"
+	"receiver/internal/search"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// @Summary Search analyzed texts
+// @Description Returns analyzed requests of the caller tenant whose texts match the query, ranked by BM25. Words match by stems, "quoted phrases" match words in a row, word* matches words by prefix, terms are combined by AND (default), OR, NOT or - and parentheses. Only inline texts are indexed: documents posted to /documents and uploads above MAX_TEXT_CHARS are never found.
+// @Tags Requests
+// @Produce json
+// @Param q query string true "Search query"
+// @Param limit query int false "Page size, 1..100 (default 20)"
+// @Param offset query int false "Number of results to skip"
+// @Success 200 {object} JsonSearchResult
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /search [get]
+func (r *Routes) search(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "search")
+	}()
+	var err error
+	limit, offset := defaultPageSize, 0
+	if param := c.Query("limit"); param != "" {
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+			return
+		}
+	}
+	if param := c.Query("offset"); param != "" {
+		offset, err = strconv.Atoi(param)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset must be a non-negative number"})
+			return
+		}
+	}
+
+	result, err := r.App.Search.Search(c.Request.Context(), c.Query("q"), tenantOf(c), limit, offset)
+	if err != nil {
+		if err == search.ErrEmptyQuery || errors.Is(err, search.ErrQuerySyntax) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		log.Error().Str("handler", "search").Err(err).Msg("search error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+		return
+	}
+
+	output := JsonSearchResult{Total: result.Total, Items: make([]JsonSearchHit, 0, len(result.Hits))}
+	for _, hit := range result.Hits {
+		output.Items = append(output.Items, JsonSearchHit(hit))
+	}
+	c.JSON(http.StatusOK, output)
+}
@@ -0,0 +1,84 @@
+package routes
+
+import (
+	"net/http"
+	"receiver/internal/metrics"
+	"receiver/internal/storage"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// similarTo return the most similar indexed requests of tenant, request exclude is skipped
+func (r *Routes) similarTo(signature storage.Signature, tenant string, exclude uuid.UUID) []storage.SimilarRequest {
+	cfg := r.App.Config.Similar
+	return r.App.Similar.Similar(signature, tenant, exclude, cfg.Limit, cfg.MinScore)
+}
+
+// @Summary List requests similar to a request
+// @Description Returns analyzed requests of the caller tenant whose texts are near-duplicates of the request text, found by MinHash LSH index and sorted by estimated Jaccard similarity.
+// @Tags Requests
+// @Produce json
+// @Param id path string true "Unique Request ID"
+// @Param limit query int false "Number of requests, 1..100 (default SIMILAR_LIMIT)"
+// @Param minScore query number false "Minimal estimated Jaccard similarity, 0..1 (default SIMILAR_MIN_SCORE)"
+// @Success 200 {object} JsonSimilarList
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /requests/{id}/similar [get]
+func (r *Routes) listSimilar(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "listSimilar")
+	}()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Error().Str("handler", "list similar").Err(err).Msg("Invalid ID")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	limit, minScore := r.App.Config.Similar.Limit, r.App.Config.Similar.MinScore
+	if param := c.Query("limit"); param != "" {
+		limit, err = strconv.Atoi(param)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be 1.." + strconv.Itoa(maxPageSize)})
+			return
+		}
+	}
+	if param := c.Query("minScore"); param != "" {
+		minScore, err = strconv.ParseFloat(param, 64)
+		if err != nil || minScore < 0 || minScore > 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "minScore must be 0..1"})
+			return
+		}
+	}
+
+	request, err := r.App.Store.Requests.GetRequest(id)
+	if err == nil && !canAccess(c, request) {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+			return
+		}
+		log.Error().Str("handler", "list similar").Str("requestID", id.String()).Err(err).Msg("found request error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list similar requests"})
+		return
+	}
+	if request.Status != storage.Success {
+		c.JSON(http.StatusConflict, gin.H{"error": "request is not analyzed"})
+		return
+	}
+
+	output := JsonSimilarList{Items: make([]JsonSimilarRequest, 0)}
+	for _, similar := range r.App.Similar.Similar(request.Analyze.Signature, request.Tenant, request.ID, limit, minScore) {
+		output.Items = append(output.Items, JsonSimilarRequest(similar))
+	}
+	c.JSON(http.StatusOK, output)
+}
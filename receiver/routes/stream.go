@@ -0,0 +1,218 @@
+package routes
+
+import (
+	"io"
+	"net/http"
+	"receiver/internal/events"
+	"receiver/internal/metrics"
+	"receiver/internal/storage"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/websocket"
+)
+
+// heartbeatInterval keep idle streams alive through proxies
+const heartbeatInterval = 15 * time.Second
+
+// @Summary Stream status changes of a request (SSE)
+// @Description Server-Sent Events stream. The current status is sent first, then every change; the stream ends after success or failed.
+// @Tags Requests
+// @Produce text/event-stream
+// @Param id path string true "Unique Request ID"
+// @Success 200 {object} events.StatusEvent "event: status"
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /status/{id}/stream [get]
+func (r *Routes) streamStatus(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "streamStatus")
+	}()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Error().Str("handler", "stream status").Err(err).Msg("Invalid ID")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+
+	// subscribe before reading state, so no change is lost in between
+	sub := r.App.Events.Subscribe(id)
+	defer sub.Close()
+	request, err := r.App.Store.Requests.GetRequest(id)
+	if err == nil && !canAccess(c, request) {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+			return
+		}
+		log.Error().Str("handler", "stream status").Str("requestID", id.String()).Err(err).Msg("found request error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get status"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("status", events.NewStatusEvent(request))
+	c.Writer.Flush()
+	if request.Status.IsTerminal() {
+		return
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-sub.C:
+			c.SSEvent("status", event)
+			return !event.Status.IsTerminal()
+		case <-heartbeat.C:
+			c.SSEvent("ping", "")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// JsonStreamCommand is a message of websocket client
+type JsonStreamCommand struct {
+	// Action is subscribe or unsubscribe
+	Action string      `json:"action"`
+	IDs    []uuid.UUID `json:"ids"`
+}
+
+// JsonStreamError is sent to websocket client instead of event
+type JsonStreamError struct {
+	ID    uuid.UUID `json:"id,omitempty"`
+	Error string    `json:"error"`
+}
+
+// @Summary Stream status changes of many requests (WebSocket)
+// @Description Initial ids are taken from the ids query param. Client may send {"action": "subscribe"|"unsubscribe", "ids": [...]}; current status of every subscribed id is sent at once, then every change.
+// @Tags Requests
+// @Param ids query string false "Comma separated request IDs"
+// @Success 101 {object} events.StatusEvent
+// @Router /ws [get]
+func (r *Routes) streamWebsocket(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "streamWebsocket")
+	}()
+	var initial []uuid.UUID
+	if ids := c.Query("ids"); ids != "" {
+		for param := range strings.SplitSeq(ids, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(param))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID " + param})
+				return
+			}
+			initial = append(initial, id)
+		}
+	}
+
+	tenant := tenantOf(c)
+	server := websocket.Server{
+		// api is not cookie authenticated, so any origin is accepted
+		Handshake: func(cfg *websocket.Config, req *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			r.serveWebsocket(ws, tenant, initial)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// serveWebsocket send events of tenant requests until client disconnect, all writes happen here
+func (r *Routes) serveWebsocket(ws *websocket.Conn, tenant string, initial []uuid.UUID) {
+	sub := r.App.Events.Subscribe()
+	defer sub.Close()
+
+	commands := make(chan JsonStreamCommand)
+	// done stop the reader if the writer returns first
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(commands)
+		for {
+			var command JsonStreamCommand
+			if err := websocket.JSON.Receive(ws, &command); err != nil {
+				if err != io.EOF {
+					log.Error().Str("handler", "stream websocket").Err(err).Msg("receive error")
+				}
+				return
+			}
+			select {
+			case commands <- command:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	if err := r.subscribeWebsocket(ws, sub, tenant, initial); err != nil {
+		return
+	}
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case command, ok := <-commands:
+			if !ok {
+				return
+			}
+			switch command.Action {
+			case "subscribe":
+				err = r.subscribeWebsocket(ws, sub, tenant, command.IDs)
+			case "unsubscribe":
+				sub.Remove(command.IDs...)
+			default:
+				err = websocket.JSON.Send(ws, JsonStreamError{Error: "action must be subscribe or unsubscribe"})
+			}
+		case event := <-sub.C:
+			err = websocket.JSON.Send(ws, event)
+		case <-heartbeat.C:
+			err = websocket.Message.Send(ws, "{}")
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// subscribeWebsocket subscribe on ids of tenant requests and send their current status
+//
+// access is checked before subscribing, so events of other tenants are never sent,
+// status is read again after subscribing, so no change is lost in between
+func (r *Routes) subscribeWebsocket(ws *websocket.Conn, sub *events.Subscription, tenant string, ids []uuid.UUID) error {
+	for _, id := range ids {
+		request, err := r.App.Store.Requests.GetRequest(id)
+		if err == nil && tenant != "" && request.Tenant != tenant {
+			err = storage.ErrNotFound
+		}
+		if err == nil {
+			sub.Add(id)
+			request, err = r.App.Store.Requests.GetRequest(id)
+			if err != nil {
+				sub.Remove(id)
+			}
+		}
+		if err != nil {
+			if err := websocket.JSON.Send(ws, JsonStreamError{ID: id, Error: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := websocket.JSON.Send(ws, events.NewStatusEvent(request)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
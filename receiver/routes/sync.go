@@ -0,0 +1,65 @@
+package routes
+
+import (
+	"net/http"
+	"receiver/internal/metrics"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Critma/textAnalyzer/shared/limits"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// @Summary Analyze small text synchronously
+// @Description Creates a request and waits for the analysis result up to the configured timeout. If the deadline is exceeded, responds 202 with the request ID, poll it with GET /status/{id}.
+// @Tags Requests
+// @Accept json
+// @Produce json
+// @Param payload body JsonTextInput true "Input text to analyze"
+// @Success 200 {object} JsonRequest
+// @Success 202 {object} JsonStatusOnlyOutput
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} limits.JsonLimitError
+// @Failure 500 {object} ErrorResponse
+// @Router /analyze/sync [post]
+func (r *Routes) handleSyncAnalyze(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "handleSyncAnalyze")
+	}()
+	req, ok := r.bindTextInput(c)
+	if !ok {
+		return
+	}
+	maxChars := r.App.Config.Sync.MaxChars
+	if utf8.RuneCountInString(req.Text) > maxChars {
+		log.Error().Str("handler", "sync analyze").Msg("Text is too long")
+		limits.WriteTooLarge(c, "Text is too long for sync analysis, use POST /text", int64(maxChars), limits.UnitChars)
+		return
+	}
+
+	chars := utf8.RuneCountInString(req.Text)
+	request, ok := r.createRequest(c, newTextRequest(req), chars)
+	if !ok {
+		return
+	}
+	// duplicate text is already analyzed
+	if request.Status.IsTerminal() {
+		writeStatus(c, http.StatusOK, request)
+		return
+	}
+	// subscribe before dispatch, analyzer may answer from cache at once
+	waiter := r.App.Events.Subscribe(request.ID)
+	defer waiter.Close()
+	if !r.dispatchRequest(c, request, chars) {
+		return
+	}
+
+	result := r.waitForStatus(c, waiter, request, r.App.Config.Sync.Timeout)
+	if !result.Status.IsTerminal() {
+		writeStatus(c, http.StatusAccepted, result)
+		return
+	}
+	writeStatus(c, http.StatusOK, result)
+}
@@ -0,0 +1,161 @@
+package routes
+
+import (
+	"net/http"
+	"path/filepath"
+	"receiver/internal/charset"
+	"receiver/internal/extract"
+	"receiver/internal/metrics"
+	"receiver/internal/storage"
+	"receiver/internal/webhook"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Critma/textAnalyzer/shared/limits"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// invalidEncodingMessage is written with 400 when text is neither UTF-8 nor in a known encoding
+const invalidEncodingMessage = "Text is not valid UTF-8 and its encoding is not recognized, supported are UTF-8, UTF-16, Windows-1251 and KOI8-R"
+
+// maxFilenameLength is a number of bytes of original filename kept in request
+const maxFilenameLength = 255
+
+// @Summary Create analysis request of an uploaded file
+// @Description Detects the file format, extracts plain text (txt, md, html, docx, odt, epub) and analyzes it. Text files in UTF-16, Windows-1251 or KOI8-R are transcoded to UTF-8. Texts above MAX_TEXT_CHARS are stored as documents and are not added to full-text search index.
+// @Tags Requests
+// @Accept mpfd
+// @Produce json
+// @Param file formData file true "Document to analyze"
+// @Param language formData string false "Language code"
+// @Param tags formData string false "Comma separated tags"
+// @Param callbackUrl formData string false "Notified with the result when analysis completes"
+// @Param normalize formData string false "Comma separated normalization steps"
+// @Success 200 {object} IdResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 413 {object} limits.JsonLimitError
+// @Failure 415 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /upload [post]
+func (r *Routes) handleUpload(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "handleUpload")
+	}()
+	sizes := r.App.Config.Limits
+	header, err := c.FormFile("file")
+	if err != nil {
+		if limits.IsTooLarge(err) {
+			log.Error().Str("handler", "handle upload").Msg("File is too large")
+			limits.WriteTooLarge(c, "File is too large", sizes.MaxDocumentBytes, limits.UnitBytes)
+			return
+		}
+		log.Error().Str("handler", "handle upload").Err(err).Msg("Invalid file")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart field file is required"})
+		return
+	}
+	callbackURL := c.PostForm("callbackUrl")
+	if callbackURL != "" && !webhook.ValidURL(callbackURL) {
+		log.Error().Str("handler", "handle upload").Str("callbackUrl", callbackURL).Msg("Invalid callback url")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "callbackUrl must be an absolute http(s) url of a public host"})
+		return
+	}
+	steps, err := parseNormalize(splitNormalize(c.PostFormArray("normalize")))
+	if err != nil {
+		log.Error().Str("handler", "handle upload").Err(err).Msg("Invalid normalization")
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Error().Str("handler", "handle upload").Err(err).Msg("Failed to open file")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+	document, err := extract.Extract(header.Filename, file, header.Size, sizes.MaxDocumentBytes)
+	if err != nil {
+		switch err {
+		case charset.ErrInvalidUTF8:
+			log.Error().Str("handler", "handle upload").Str("filename", header.Filename).Msg("Invalid encoding")
+			c.JSON(http.StatusBadRequest, gin.H{"error": invalidEncodingMessage})
+		case extract.ErrUnsupported:
+			log.Error().Str("handler", "handle upload").Str("filename", header.Filename).Msg("Unsupported file format")
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported file format, expected txt, md, html, docx, odt or epub"})
+		case extract.ErrTooLarge:
+			log.Error().Str("handler", "handle upload").Str("filename", header.Filename).Msg("Extracted text is too large")
+			limits.WriteTooLarge(c, "Extracted text is too large", sizes.MaxDocumentBytes, limits.UnitBytes)
+		default:
+			log.Error().Str("handler", "handle upload").Str("filename", header.Filename).Err(err).Msg("Failed to extract text")
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to extract text from file"})
+		}
+		return
+	}
+	text := document.Text
+	if strings.TrimSpace(text) == "" {
+		log.Error().Str("handler", "handle upload").Str("filename", header.Filename).Msg("No text in file")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No text in file"})
+		return
+	}
+
+	var tags []string
+	for _, param := range c.PostFormArray("tags") {
+		tags = append(tags, strings.Split(param, ",")...)
+	}
+	input := storage.TextRequest{
+		Language:    normalizeLanguage(c.PostForm("language")),
+		Tags:        normalizeTags(tags),
+		Filename:    normalizeFilename(header.Filename),
+		Format:      string(document.Format),
+		Encoding:    string(document.Encoding),
+		CallbackURL: callbackURL,
+		ContentHash: contentHash(text, steps),
+		Normalize:   steps,
+	}
+	chars := utf8.RuneCountInString(text)
+	// long texts are analyzed as documents
+	if sizes.MaxTextChars > 0 && chars > sizes.MaxTextChars {
+		stored, err := r.App.Blobs.Put(strings.NewReader(text))
+		if err != nil {
+			log.Error().Str("handler", "handle upload").Err(err).Msg("Failed to store document")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+			return
+		}
+		input.Blob, input.Size = stored.ID, stored.Size
+	} else {
+		input.Text = text
+	}
+
+	request, ok := r.createRequest(c, input, chars)
+	if !ok {
+		if input.Blob != "" {
+			if err := r.App.Blobs.Delete(input.Blob); err != nil {
+				log.Error().Str("handler", "handle upload").Str("blob", input.Blob).Err(err).Msg("Failed to delete blob")
+			}
+		}
+		return
+	}
+	// duplicate text is already analyzed
+	if !request.Status.IsTerminal() && !r.dispatchRequest(c, request, chars) {
+		return
+	}
+
+	c.JSON(http.StatusOK, IdResponse{ID: request.ID.String(), Status: request.Status})
+}
+
+// normalizeFilename return base name of uploaded file, cut to maxFilenameLength bytes
+func normalizeFilename(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || name == "/" {
+		return ""
+	}
+	for len(name) > maxFilenameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
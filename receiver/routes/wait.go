@@ -0,0 +1,59 @@
+package routes
+
+import (
+	"errors"
+	"receiver/internal/events"
+	"receiver/internal/storage"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// maxWait limit long polling duration
+const maxWait = 60 * time.Second
+
+var errInvalidWait = errors.New("wait must be a positive duration up to 60s")
+
+// parseWait return long polling duration, 0 if wait is empty
+func parseWait(wait string) (time.Duration, error) {
+	if wait == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(wait)
+	if err != nil || d <= 0 || d > maxWait {
+		return 0, errInvalidWait
+	}
+	return d, nil
+}
+
+// waitForStatus block until request reach terminal status, wait expire or client go away
+//
+// return fresh request from store, or current if nothing changed
+func (r *Routes) waitForStatus(c *gin.Context, waiter *events.Subscription, current *storage.TextRequest, wait time.Duration) *storage.TextRequest {
+	// cached status may be behind the store
+	if fresh, err := r.App.Store.Requests.GetRequest(current.ID); err == nil && fresh.Status.IsTerminal() {
+		return fresh
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case event := <-waiter.C:
+			if !event.Status.IsTerminal() {
+				continue
+			}
+			result, err := r.App.Store.Requests.GetRequest(current.ID)
+			if err != nil {
+				log.Error().Str("handler", "get status").Str("requestID", current.ID.String()).Err(err).Msg("found request error")
+				return current
+			}
+			return result
+		case <-timer.C:
+			return current
+		case <-c.Request.Context().Done():
+			return current
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package routes
+
+import (
+	"net/http"
+	"receiver/internal/metrics"
+	"receiver/internal/storage"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// @Summary List callback deliveries of a request
+// @Description Returns every attempt to deliver the analysis result to the request callbackUrl.
+// @Tags Requests
+// @Produce json
+// @Param id path string true "Unique Request ID"
+// @Success 200 {array} JsonWebhookDelivery
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /requests/{id}/deliveries [get]
+func (r *Routes) listDeliveries(c *gin.Context) {
+	start := time.Now()
+	defer func() {
+		metrics.ObserveRequest(time.Since(start), c.Writer.Status(), "listDeliveries")
+	}()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		log.Error().Str("handler", "list deliveries").Err(err).Msg("Invalid ID")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ID"})
+		return
+	}
+	request, err := r.App.Store.Requests.GetRequest(id)
+	if err == nil && !canAccess(c, request) {
+		err = storage.ErrNotFound
+	}
+	if err != nil {
+		if err == storage.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "request not found"})
+			return
+		}
+		log.Error().Str("handler", "list deliveries").Str("requestID", id.String()).Err(err).Msg("found request error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list deliveries"})
+		return
+	}
+
+	deliveries, err := r.App.Store.Deliveries.ListDeliveries(id)
+	if err != nil {
+		log.Error().Str("handler", "list deliveries").Str("requestID", id.String()).Err(err).Msg("list deliveries error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list deliveries"})
+		return
+	}
+	result := make([]JsonWebhookDelivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		result = append(result, JsonWebhookDelivery{
+			ID:         delivery.ID,
+			URL:        delivery.URL,
+			Attempt:    delivery.Attempt,
+			StatusCode: delivery.StatusCode,
+			Error:      delivery.Error,
+			Success:    delivery.Success,
+			At:         delivery.At,
+			DurationMs: delivery.Duration.Milliseconds(),
+		})
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,55 @@
+package limits
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// units of exceeded limits
+const (
+	UnitBytes = "bytes"
+	UnitChars = "chars"
+	UnitWords = "words"
+)
+
+// JsonLimitError is written with 413 when request exceeds a size limit
+type JsonLimitError struct {
+	Error string `json:"error"`
+	Limit int64  `json:"limit"`
+	// Unit of Limit: bytes, chars or words
+	Unit string `json:"unit"`
+}
+
+// Body reject requests with body larger than n bytes
+//
+// declared Content-Length is checked at once, body of unknown length is cut while it is read
+func Body(n int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if n <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > n {
+			log.Error().Str("handler", "limit body").Int64("contentLength", c.Request.ContentLength).Msg("Request body is too large")
+			WriteTooLarge(c, "Request body is too large", n, UnitBytes)
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
+		c.Next()
+	}
+}
+
+// IsTooLarge report whether err is caused by body limit
+func IsTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// WriteTooLarge write 413 with exceeded limit
+func WriteTooLarge(c *gin.Context, message string, limit int64, unit string) {
+	c.JSON(http.StatusRequestEntityTooLarge, JsonLimitError{Error: message, Limit: limit, Unit: unit})
+}
@@ -0,0 +1,156 @@
+package normalize
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html"
+	"slices"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Step of normalization pipeline
+type Step string
+
+var (
+	// Entities decode html entities (&amp;, &nbsp;, &#1105;)
+	Entities Step = "entities"
+	// Invisible strip zero-width, bidi, soft hyphen and other format and control characters
+	Invisible Step = "invisible"
+	// NFC compose characters, NFD accents become single characters
+	NFC Step = "nfc"
+	// NFKC compose characters and replace compatibility ones (ligatures, full-width, non-breaking space)
+	NFKC Step = "nfkc"
+	// Yo fold ё to е
+	Yo Step = "yo"
+	// Quotes replace typographic quotes with ASCII ones
+	Quotes Step = "quotes"
+	// Whitespace replace runs of spaces (including non-breaking) with a single space, line breaks with \n
+	Whitespace Step = "whitespace"
+)
+
+// pipeline is the order steps are applied in
+var pipeline = []Step{Entities, Invisible, NFC, NFKC, Yo, Quotes, Whitespace}
+
+var (
+	ErrUnknownStep = errors.New("unknown normalization step, expected entities, invisible, nfc, nfkc, yo, quotes or whitespace")
+	ErrNormForms   = errors.New("nfc and nfkc can't be used together")
+)
+
+// Steps is a normalization pipeline in canonical order
+type Steps []Step
+
+// Parse return steps of names in canonical order, repeated names are ignored
+func Parse(names []string) (Steps, error) {
+	var steps Steps
+	for _, name := range names {
+		step := Step(strings.ToLower(strings.TrimSpace(name)))
+		if !slices.Contains(pipeline, step) {
+			return nil, ErrUnknownStep
+		}
+		if !slices.Contains(steps, step) {
+			steps = append(steps, step)
+		}
+	}
+	if slices.Contains(steps, NFC) && slices.Contains(steps, NFKC) {
+		return nil, ErrNormForms
+	}
+	slices.SortFunc(steps, func(a, b Step) int {
+		return slices.Index(pipeline, a) - slices.Index(pipeline, b)
+	})
+	return steps, nil
+}
+
+// String return canonical form of steps, empty if there are none
+func (s Steps) String() string {
+	return strings.Join(s.Names(), ",")
+}
+
+// Names return names of steps, nil if there are none
+func (s Steps) Names() []string {
+	var names []string
+	for _, step := range s {
+		names = append(names, string(step))
+	}
+	return names
+}
+
+// Apply return text normalized by steps
+func (s Steps) Apply(text string) string {
+	for _, step := range s {
+		switch step {
+		case Entities:
+			text = html.UnescapeString(text)
+		case Invisible:
+			text = strings.Map(func(r rune) rune {
+				if unicode.Is(unicode.Cf, r) || unicode.IsControl(r) && r != '\n' && r != '\t' && r != '\r' {
+					return -1
+				}
+				return r
+			}, text)
+		case NFC:
+			text = norm.NFC.String(text)
+		case NFKC:
+			text = norm.NFKC.String(text)
+		case Yo:
+			text = yoReplacer.Replace(text)
+		case Quotes:
+			text = quotesReplacer.Replace(text)
+		case Whitespace:
+			text = collapseWhitespace(text)
+		}
+	}
+	return text
+}
+
+// Hash return hex SHA-256 of steps and text normalized by them,
+// texts analyzed the same way have the same hash
+func (s Steps) Hash(text string) string {
+	return s.HashNormalized(s.Apply(text))
+}
+
+// HashNormalized return Hash of text already normalized by steps
+func (s Steps) HashNormalized(normalized string) string {
+	hash := sha256.New()
+	hash.Write([]byte(s.String()))
+	hash.Write([]byte{0})
+	hash.Write([]byte(normalized))
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// yoReplacer fold composed and decomposed ё
+var yoReplacer = strings.NewReplacer("ё", "е", "Ё", "Е", "е\u0308", "е", "Е\u0308", "Е")
+
+var quotesReplacer = strings.NewReplacer(
+	"«", `"`, "»", `"`, "“", `"`, "”", `"`, "„", `"`, "‟", `"`, "″", `"`, "〝", `"`, "〞", `"`,
+	"‘", "'", "’", "'", "‚", "'", "‛", "'", "′", "'", "‹", "'", "›", "'",
+)
+
+// collapseWhitespace replace runs of spaces with a single space and line breaks (\r\n, \r, U+2028, U+2029) with \n,
+// spaces around line breaks are dropped
+func collapseWhitespace(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	space := false
+	for i, r := range text {
+		switch {
+		case r == '\r' && i+1 < len(text) && text[i+1] == '\n':
+			// \r\n is a single line break
+		case r == '\n' || r == '\r' || r == '\u2028' || r == '\u2029':
+			space = false
+			b.WriteByte('\n')
+		case unicode.IsSpace(r):
+			space = true
+		default:
+			if space && b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
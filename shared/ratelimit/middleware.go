@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	LimitHeader      = "RateLimit-Limit"
+	RemainingHeader  = "RateLimit-Remaining"
+	ResetHeader      = "RateLimit-Reset"
+	RetryAfterHeader = "Retry-After"
+)
+
+// Middleware take a token from every enabled bucket of request, write 429 if one is empty
+//
+// RateLimit-* headers describe the first bucket, or the bucket which rejected request;
+// observe count result (allowed, throttled, error) of every bucket scope;
+// limiter errors do not block requests
+func (l *Limiter) Middleware(observe func(scope, result string), bucketsOf func(c *gin.Context) []Bucket) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var buckets []Bucket
+		for _, bucket := range bucketsOf(c) {
+			if bucket.Limit.Enabled() {
+				buckets = append(buckets, bucket)
+			}
+		}
+		if len(buckets) == 0 {
+			c.Next()
+			return
+		}
+
+		allowed, results, err := l.Allow(context.Background(), buckets)
+		if err != nil {
+			log.Error().Str("handler", "rate limit").Err(err).Msg("rate limiter unavailable, request is not limited")
+			for _, bucket := range buckets {
+				observe(bucket.Scope, "error")
+			}
+			c.Next()
+			return
+		}
+
+		rejected := -1
+		for i, bucket := range buckets {
+			switch {
+			case allowed:
+				observe(bucket.Scope, "allowed")
+			case !results[i].Allowed:
+				observe(bucket.Scope, "throttled")
+				if rejected < 0 {
+					rejected = i
+				}
+			}
+		}
+		if allowed {
+			setHeaders(c, results[0])
+			c.Next()
+			return
+		}
+		setHeaders(c, results[rejected])
+		c.Header(RetryAfterHeader, headerSeconds(results[rejected].RetryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+	}
+}
+
+func setHeaders(c *gin.Context, result Result) {
+	c.Header(LimitHeader, strconv.Itoa(result.Limit))
+	c.Header(RemainingHeader, strconv.Itoa(result.Remaining))
+	c.Header(ResetHeader, headerSeconds(result.Reset))
+}
+
+// headerSeconds return d rounded up to whole seconds
+func headerSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
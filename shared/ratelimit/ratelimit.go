@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const limitObj = "ratelimit"
+
+// Limit is a token bucket refilled with Rate tokens per second up to Burst
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled report whether limit is set, zero rate is unlimited
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Bucket of requests of Key limited by Limit, Scope label its metrics
+type Bucket struct {
+	Scope string
+	Key   string
+	Limit Limit
+}
+
+// Result of taking a token from bucket
+type Result struct {
+	Allowed bool
+	// Limit is a bucket size
+	Limit     int
+	Remaining int
+	// Reset is a time until bucket is full again
+	Reset time.Duration
+	// RetryAfter is a time until next token, zero if allowed
+	RetryAfter time.Duration
+}
+
+// takeTokens refill buckets KEYS with rate ARGV[2i-1] up to burst ARGV[2i] and take one token from each of them,
+// if every bucket has a token, return {1 if taken, has token and tokens left of every bucket...}
+//
+// time is taken from redis, so replicas with skewed clocks share one bucket correctly
+var takeTokens = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) + tonumber(time[2]) / 1000000
+local tokens = {}
+local allowed = 1
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[2 * i - 1])
+	local burst = tonumber(ARGV[2 * i])
+	local bucket = redis.call('HMGET', key, 'tokens', 'ts')
+	local left = tonumber(bucket[1])
+	local ts = tonumber(bucket[2])
+	if left == nil or ts == nil then
+		left = burst
+		ts = now
+	end
+	tokens[i] = math.min(burst, left + math.max(0, now - ts) * rate)
+	if tokens[i] < 1 then
+		allowed = 0
+	end
+end
+local reply = {allowed}
+for i, key in ipairs(KEYS) do
+	local rate = tonumber(ARGV[2 * i - 1])
+	local burst = tonumber(ARGV[2 * i])
+	local taken = 0
+	if tokens[i] >= 1 then
+		taken = 1
+	end
+	if allowed == 1 then
+		tokens[i] = tokens[i] - 1
+	end
+	redis.call('HSET', key, 'tokens', tostring(tokens[i]), 'ts', tostring(now))
+	redis.call('PEXPIRE', key, math.ceil(burst / rate * 1000) + 1000)
+	table.insert(reply, taken)
+	table.insert(reply, tostring(tokens[i]))
+end
+return reply
+`)
+
+// Limiter keep token buckets in redis, shared by all replicas
+type Limiter struct {
+	rdb    *redis.Client
+	prefix string
+}
+
+// New return limiter with buckets at ratelimit:{prefix}:{key}
+func New(rdb *redis.Client, prefix string) *Limiter {
+	return &Limiter{rdb: rdb, prefix: prefix}
+}
+
+// Allow take a token from every bucket in one redis call, return result of each of them
+//
+// tokens are taken only if every bucket has one, request is allowed then;
+// Result.Allowed is false for buckets which are empty
+func (l *Limiter) Allow(ctx context.Context, buckets []Bucket) (bool, []Result, error) {
+	keys := make([]string, len(buckets))
+	args := make([]any, 0, 2*len(buckets))
+	for i, bucket := range buckets {
+		keys[i] = l.redisKey(bucket.Key)
+		args = append(args, bucket.Limit.Rate, bucket.Limit.Burst)
+	}
+	values, err := takeTokens.Run(ctx, l.rdb, keys, args...).Slice()
+	if err != nil {
+		return false, nil, err
+	}
+	if len(values) != 1+2*len(buckets) {
+		return false, nil, fmt.Errorf("unexpected rate limit reply %v", values)
+	}
+	allowed, _ := values[0].(int64)
+
+	results := make([]Result, len(buckets))
+	for i, bucket := range buckets {
+		taken, _ := values[1+2*i].(int64)
+		tokens, err := strconv.ParseFloat(fmt.Sprint(values[2+2*i]), 64)
+		if err != nil {
+			return false, nil, err
+		}
+		limit := bucket.Limit
+		results[i] = Result{
+			Allowed:   taken == 1,
+			Limit:     limit.Burst,
+			Remaining: int(math.Floor(tokens)),
+			Reset:     seconds((float64(limit.Burst) - tokens) / limit.Rate),
+		}
+		if !results[i].Allowed {
+			results[i].RetryAfter = seconds((1 - tokens) / limit.Rate)
+		}
+	}
+	return allowed == 1, results, nil
+}
+
+func (l *Limiter) redisKey(key string) string {
+	return fmt.Sprintf("%s:%s:%s", limitObj, l.prefix, key)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
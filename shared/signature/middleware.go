@@ -0,0 +1,43 @@
+package signature
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+
+	"github.com/Critma/textAnalyzer/shared/limits"
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Middleware reject requests without valid signature, maxBodyBytes is reported if body exceeds limits.Body
+func (v *Verifier) Middleware(maxBodyBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			if limits.IsTooLarge(err) {
+				limits.WriteTooLarge(c, "Request body is too large", maxBodyBytes, limits.UnitBytes)
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		// body is read again by handler
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		if err := v.Verify(context.Background(), c.Request.Header, body); err != nil {
+			switch err {
+			case ErrMissingSignature, ErrInvalidSignature, ErrExpired, ErrReplayed:
+				log.Error().Str("handler", "verify signature").Str("remoteAddr", c.ClientIP()).Err(err).Msg("rejected internal request")
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			default:
+				log.Error().Str("handler", "verify signature").Err(err).Msg("nonce store error")
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Failed to verify signature"})
+			}
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,107 @@
+package signature
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// headers of signed service-to-service requests
+const (
+	TimestampHeader = "X-Internal-Timestamp"
+	NonceHeader     = "X-Internal-Nonce"
+	SignatureHeader = "X-Internal-Signature"
+)
+
+const (
+	nonceObj = "internal:nonce"
+	// maxNonceLength keep redis keys short
+	maxNonceLength = 64
+)
+
+var (
+	ErrMissingSignature = errors.New("missing signature headers")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("timestamp is out of allowed skew")
+	ErrReplayed         = errors.New("request is already received")
+)
+
+// Sign return hex HMAC-SHA256 of "{timestamp}.{nonce}.{body}"
+func Sign(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s.%s.", timestamp, nonce)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignRequest set signature headers of req with body
+func SignRequest(req *http.Request, secret string, body []byte) error {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	nonceHex := hex.EncodeToString(nonce)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(NonceHeader, nonceHex)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(secret, timestamp, nonceHex, body))
+	return nil
+}
+
+// Verifier check signatures of requests from other services
+//
+// a nonce is accepted once within twice the allowed skew, so a captured request cannot be replayed
+type Verifier struct {
+	rdb     *redis.Client
+	secret  string
+	maxSkew time.Duration
+}
+
+// NewVerifier return verifier of requests signed with secret
+func NewVerifier(rdb *redis.Client, secret string, maxSkew time.Duration) *Verifier {
+	return &Verifier{rdb: rdb, secret: secret, maxSkew: maxSkew}
+}
+
+// Verify check signature headers of request with body
+func (v *Verifier) Verify(ctx context.Context, header http.Header, body []byte) error {
+	timestamp := header.Get(TimestampHeader)
+	nonce := header.Get(NonceHeader)
+	signature, _ := strings.CutPrefix(header.Get(SignatureHeader), "sha256=")
+	if timestamp == "" || nonce == "" || signature == "" || len(nonce) > maxNonceLength {
+		return ErrMissingSignature
+	}
+
+	expected := Sign(v.secret, timestamp, nonce, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	skew := time.Since(time.Unix(unix, 0))
+	if skew > v.maxSkew || skew < -v.maxSkew {
+		return ErrExpired
+	}
+
+	// signature is valid, so nonce is not forged and may be remembered
+	fresh, err := v.rdb.SetNX(ctx, fmt.Sprintf("%s:%s", nonceObj, nonce), timestamp, 2*v.maxSkew).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrReplayed
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package tiered
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru is a bounded in-memory cache with ttl, safe for concurrent use
+type lru[V any] struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+	// onEvict is called when an item is dropped to free space
+	onEvict func()
+}
+
+type lruItem[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+// newLRU return cache of size items, size <= 0 disable caching
+func newLRU[V any](size int, onEvict func()) *lru[V] {
+	return &lru[V]{
+		size:    size,
+		order:   list.New(),
+		items:   make(map[string]*list.Element),
+		onEvict: onEvict,
+	}
+}
+
+func (c *lru[V]) get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var zero V
+	elem, ok := c.items[key]
+	if !ok {
+		return zero, false
+	}
+	item := elem.Value.(*lruItem[V])
+	if time.Now().After(item.expires) {
+		c.remove(elem)
+		return zero, false
+	}
+	c.order.MoveToFront(elem)
+	return item.value, true
+}
+
+func (c *lru[V]) set(key string, value V, ttl time.Duration) {
+	c.setIf(key, value, ttl, nil)
+}
+
+// setIf set value unless key is presented and replace(old value) is false,
+// nil replace always set, return whether value is set
+func (c *lru[V]) setIf(key string, value V, ttl time.Duration, replace func(old V) bool) bool {
+	if c.size <= 0 || ttl <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := time.Now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		item := elem.Value.(*lruItem[V])
+		if replace != nil && time.Now().Before(item.expires) && !replace(item.value) {
+			return false
+		}
+		item.value = value
+		item.expires = expires
+		c.order.MoveToFront(elem)
+		return true
+	}
+	c.items[key] = c.order.PushFront(&lruItem[V]{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+		if c.onEvict != nil {
+			c.onEvict()
+		}
+	}
+	return true
+}
+
+// deleteIf delete key if it is presented and match its value
+func (c *lru[V]) deleteIf(key string, match func(value V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok && match(elem.Value.(*lruItem[V]).value) {
+		c.remove(elem)
+	}
+}
+
+func (c *lru[V]) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.items[key]; ok {
+		c.remove(elem)
+	}
+}
+
+// deleteFunc delete every item with key matching match
+func (c *lru[V]) deleteFunc(match func(key string) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, elem := range c.items {
+		if match(key) {
+			c.remove(elem)
+		}
+	}
+}
+
+// remove must be called under mu
+func (c *lru[V]) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruItem[V]).key)
+}
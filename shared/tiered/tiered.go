@@ -0,0 +1,205 @@
+package tiered
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	tierLocal = "local"
+	tierRedis = "redis"
+)
+
+var (
+	// ErrMiss is returned by Remote when key is not presented
+	ErrMiss = errors.New("cache miss")
+	// ErrStale is returned by Remote.Set when stored entry has greater revision
+	ErrStale = errors.New("stale cache entry")
+)
+
+// Entry is a cached value or a cached absence of it
+type Entry[V any] struct {
+	Value    V    `json:"value"`
+	NotFound bool `json:"notFound,omitempty"`
+	// Revision of versioned value, entry never replace an entry with greater revision
+	Revision uint64 `json:"revision,omitempty"`
+}
+
+// Remote is a shared cache tier
+//
+// Set must not replace an entry with greater revision, return ErrStale instead
+type Remote[V any] interface {
+	Get(ctx context.Context, key string) (Entry[V], error)
+	Set(ctx context.Context, key string, entry Entry[V], ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type Options struct {
+	// Name label metrics of cache
+	Name string
+	// Observe count cache event (hit, miss, eviction, error, stale) of tier, nil disable metrics
+	Observe func(cache, tier, event string)
+	// LocalSize is a max number of in-memory items, 0 disable local tier
+	LocalSize int
+	LocalTTL  time.Duration
+	// TTL of remote tier
+	TTL time.Duration
+	// NegativeTTL of cached NotFound in both tiers
+	NegativeTTL time.Duration
+	// NotFound error of loader is cached for NegativeTTL, nil disable negative caching
+	NotFound error
+}
+
+// Cache is a two-tier cache: in-process LRU in front of a shared remote
+//
+// remote errors are logged and counted, then treated as a miss
+type Cache[V any] struct {
+	opts   Options
+	local  *lru[Entry[V]]
+	remote Remote[V]
+	group  singleflight.Group
+	// revision of value, nil if values are not versioned
+	revision func(V) uint64
+}
+
+func New[V any](remote Remote[V], opts Options) *Cache[V] {
+	if opts.Observe == nil {
+		opts.Observe = func(cache, tier, event string) {}
+	}
+	return &Cache[V]{
+		opts: opts,
+		local: newLRU[Entry[V]](opts.LocalSize, func() {
+			opts.Observe(opts.Name, tierLocal, "eviction")
+		}),
+		remote: remote,
+	}
+}
+
+// NewVersioned return cache of versioned values, a value with lower revision never replace a newer one
+func NewVersioned[V any](remote Remote[V], opts Options, revision func(V) uint64) *Cache[V] {
+	t := New(remote, opts)
+	t.revision = revision
+	return t
+}
+
+// Get return cached value and true, or false on miss
+//
+// cached absence return true and opts.NotFound error
+func (t *Cache[V]) Get(ctx context.Context, key string) (V, bool, error) {
+	if entry, ok := t.local.get(key); ok {
+		t.opts.Observe(t.opts.Name, tierLocal, "hit")
+		return t.unwrap(entry)
+	}
+	t.opts.Observe(t.opts.Name, tierLocal, "miss")
+
+	entry, err := t.remote.Get(ctx, key)
+	if err != nil {
+		var zero V
+		if err != ErrMiss {
+			t.opts.Observe(t.opts.Name, tierRedis, "error")
+			log.Error().Str("event", "cache get").Str("cache", t.opts.Name).Str("key", key).Err(err).Msg("remote cache error")
+		}
+		t.opts.Observe(t.opts.Name, tierRedis, "miss")
+		return zero, false, nil
+	}
+	t.opts.Observe(t.opts.Name, tierRedis, "hit")
+	t.local.setIf(key, entry, t.localTTL(entry), entry.newer)
+	return t.unwrap(entry)
+}
+
+// Set cache value in both tiers
+func (t *Cache[V]) Set(ctx context.Context, key string, value V) {
+	t.set(ctx, key, Entry[V]{Value: value}, t.opts.TTL)
+}
+
+// SetNotFound cache absence of key in both tiers
+func (t *Cache[V]) SetNotFound(ctx context.Context, key string) {
+	t.set(ctx, key, Entry[V]{NotFound: true}, t.opts.NegativeTTL)
+}
+
+// Delete remove key from both tiers
+func (t *Cache[V]) Delete(ctx context.Context, key string) error {
+	t.local.delete(key)
+	return t.remote.Delete(ctx, key)
+}
+
+// Invalidate remove local entry of key older than revision, used when value is changed by another process
+func (t *Cache[V]) Invalidate(key string, revision uint64) {
+	t.local.deleteIf(key, func(entry Entry[V]) bool { return entry.Revision < revision })
+}
+
+// DeleteLocal remove keys matching match from local tier only
+func (t *Cache[V]) DeleteLocal(match func(key string) bool) {
+	t.local.deleteFunc(match)
+}
+
+// Load return cached value, or call load once for concurrent callers of key and cache its result
+func (t *Cache[V]) Load(ctx context.Context, key string, load func() (V, error)) (V, error) {
+	if value, ok, err := t.Get(ctx, key); ok {
+		return value, err
+	}
+	result, err, _ := t.group.Do(key, func() (any, error) {
+		value, err := load()
+		if err != nil {
+			if t.opts.NotFound != nil && errors.Is(err, t.opts.NotFound) {
+				t.SetNotFound(ctx, key)
+			}
+			return value, err
+		}
+		t.Set(ctx, key, value)
+		return value, nil
+	})
+	return result.(V), err
+}
+
+func (t *Cache[V]) set(ctx context.Context, key string, entry Entry[V], ttl time.Duration) {
+	if entry.NotFound && t.opts.NotFound == nil {
+		return
+	}
+	if t.revision != nil && !entry.NotFound {
+		entry.Revision = t.revision(entry.Value)
+	}
+	if !t.local.setIf(key, entry, t.localTTL(entry), entry.newer) {
+		t.opts.Observe(t.opts.Name, tierLocal, "stale")
+	}
+	err := t.remote.Set(ctx, key, entry, ttl)
+	if err == nil {
+		return
+	}
+	if err == ErrStale {
+		// remote has a newer entry, drop local one so it is read from remote
+		t.opts.Observe(t.opts.Name, tierRedis, "stale")
+		t.local.deleteIf(key, func(local Entry[V]) bool { return local.Revision <= entry.Revision })
+		return
+	}
+	t.opts.Observe(t.opts.Name, tierRedis, "error")
+	log.Error().Str("event", "cache set").Str("cache", t.opts.Name).Str("key", key).Err(err).Msg("remote cache error")
+	// previous remote entry is outdated now, keep it from being served
+	if err := t.remote.Delete(ctx, key); err != nil {
+		log.Error().Str("event", "cache delete").Str("cache", t.opts.Name).Str("key", key).Err(err).Msg("remote cache error")
+	}
+}
+
+// localTTL return local ttl of entry, not longer than its remote ttl
+func (t *Cache[V]) localTTL(entry Entry[V]) time.Duration {
+	if entry.NotFound {
+		return min(t.opts.LocalTTL, t.opts.NegativeTTL)
+	}
+	return min(t.opts.LocalTTL, t.opts.TTL)
+}
+
+func (t *Cache[V]) unwrap(entry Entry[V]) (V, bool, error) {
+	if entry.NotFound {
+		return entry.Value, true, t.opts.NotFound
+	}
+	return entry.Value, true, nil
+}
+
+// newer report whether entry may replace old one
+func (entry Entry[V]) newer(old Entry[V]) bool {
+	return entry.Revision >= old.Revision
+}